@@ -0,0 +1,166 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/bodgit/sevenzip"
+)
+
+// archives nested inside other archives are expanded up to this depth
+const maxArchiveDepth = 8
+
+// the separator between the name of an archive and the path of a file inside
+// the archive. for example, archive.zip!/path/inside.bin
+const archiveSeparator = "!"
+
+// a single file extracted from an archive
+type archiveEntry struct {
+	name string
+	data []byte
+}
+
+// the types of archive that can be expanded by expandArchive()
+type archiveType int
+
+const (
+	notArchive archiveType = iota
+	zipArchive
+	sevenZipArchive
+	tarArchive
+	tarGzipArchive
+)
+
+// identifyArchive decides whether the data is an archive. zip and 7z archives
+// are identified by their content. tar and gzipped tar archives are identified
+// by the file extension
+func identifyArchive(filename string, data []byte) archiveType {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return zipArchive
+	case bytes.HasPrefix(data, []byte("7z\xbc\xaf\x27\x1c")):
+		return sevenZipArchive
+	}
+
+	fn := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(fn, ".tar.gz") || strings.HasSuffix(fn, ".tgz"):
+		if bytes.HasPrefix(data, []byte("\x1f\x8b")) {
+			return tarGzipArchive
+		}
+	case strings.HasSuffix(fn, ".tar"):
+		return tarArchive
+	}
+
+	return notArchive
+}
+
+// isArchiveName returns true if the filename has the extension of an archive
+// that can be expanded by expandArchive()
+func isArchiveName(filename string) bool {
+	fn := strings.ToLower(filename)
+	for _, ext := range []string{".zip", ".7z", ".tar", ".tar.gz", ".tgz"} {
+		if strings.HasSuffix(fn, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandArchive returns every file in the archive. directories are not
+// returned but the names of files include the path inside the archive
+func expandArchive(typ archiveType, data []byte) ([]archiveEntry, error) {
+	var entries []archiveEntry
+
+	switch typ {
+	case zipArchive:
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("zip: %w", err)
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			r, err := f.Open()
+			if err != nil {
+				return nil, fmt.Errorf("zip: %w", err)
+			}
+			d, err := io.ReadAll(r)
+			r.Close()
+			if err != nil {
+				return nil, fmt.Errorf("zip: %w", err)
+			}
+			entries = append(entries, archiveEntry{name: f.Name, data: d})
+		}
+
+	case sevenZipArchive:
+		zr, err := sevenzip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("7z: %w", err)
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			r, err := f.Open()
+			if err != nil {
+				return nil, fmt.Errorf("7z: %w", err)
+			}
+			d, err := io.ReadAll(r)
+			r.Close()
+			if err != nil {
+				return nil, fmt.Errorf("7z: %w", err)
+			}
+			entries = append(entries, archiveEntry{name: f.Name, data: d})
+		}
+
+	case tarArchive, tarGzipArchive:
+		var r io.Reader = bytes.NewReader(data)
+		if typ == tarGzipArchive {
+			gr, err := gzip.NewReader(r)
+			if err != nil {
+				return nil, fmt.Errorf("tar: %w", err)
+			}
+			defer gr.Close()
+			r = gr
+		}
+
+		tr := tar.NewReader(r)
+		for {
+			hdr, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("tar: %w", err)
+			}
+			if hdr.Typeflag != tar.TypeReg {
+				continue
+			}
+			d, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, fmt.Errorf("tar: %w", err)
+			}
+			entries = append(entries, archiveEntry{name: hdr.Name, data: d})
+		}
+	}
+
+	return entries, nil
+}
+
+// archiveName joins the name of an archive with the path of a file inside the
+// archive. the path inside the archive always uses forward slashes
+func archiveName(archive string, inside string) string {
+	inside = filepath.ToSlash(inside)
+	if !strings.HasPrefix(inside, "/") {
+		inside = "/" + inside
+	}
+	return archive + archiveSeparator + inside
+}
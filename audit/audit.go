@@ -1,37 +1,276 @@
 package main
 
 import (
+	"context"
+	"crypto/md5"
 	"errors"
 	"flag"
 	"fmt"
+	"image"
+	"io"
 	"log"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/jetsetilly/gopher2600-utils/audit/auditors"
 	"github.com/jetsetilly/gopher2600/archivefs"
 	"github.com/jetsetilly/gopher2600/cartridgeloader"
 	"github.com/jetsetilly/gopher2600/debugger/govern"
-	"github.com/jetsetilly/gopher2600/environment"
-	"github.com/jetsetilly/gopher2600/hardware"
-	"github.com/jetsetilly/gopher2600/hardware/television"
+	"github.com/jetsetilly/gopher2600/hardware/riot/ports/plugging"
+	"github.com/jetsetilly/gopher2600/hardware/television/specification"
 )
 
+// the value of the auditor option that causes every auditor to run
+const allAuditors = "ALL"
+
+// the ID used for the result of a file that could not be loaded
+const loaderAuditorID = "Loader"
+
 type audit struct {
 	// command line options
 	recurse    bool
 	concurrent bool
+	workers    int
+	progress   progress
 	auditor    string
+	rules      string
+	script     string
+	html       string
+	json       string
+	failOn     string
+	tv         string
+	mapper     string
+	override   string
+	specs      bool
+	include    string
+	exclude    string
+	ext        string
+	minSize    string
+	maxSize    string
+	randomise  string
+	seed       int64
+	stability  int
+	profile    bool
+	db         string
+	snapshots  string
+	recordings string
+
+	// filter created from the include, exclude, ext and size options
+	filter filter
+
+	// the state of the VCS at the start of each audit. created from the
+	// randomise and seed options
+	startup startup
+
+	// catalogue options. nil unless the catalogue subcommand is being run
+	catalogue *catalogue
+
+	// ROM database loaded from the file named by the db option. nil if the
+	// option is not set
+	database romDatabase
+
+	// playback recordings found in the directory named by the recordings
+	// option. nil if the option is not set
+	recs *recordings
+
+	// throughput of the emulator. nil if the bench option is not set
+	bench *benchmark
+
+	// overrides of the tv and mapper options for specific ROMs
+	overrides []override
+
+	// the auditors to run on each ROM. normalised IDs suitable for use as keys
+	// in the auditors.Factory
+	auditors []string
+
+	// parameters for auditors keyed by normalised ID. see auditors.Configure()
+	params map[string]string
 
 	// keep track of which roms have been audited. prevents reporting on
 	// duplicate ROM files. key values are MD5 sums of cartridge data
 	completed map[string][]string
+
+	// the results of every audited ROM in the order they were found
+	results []*romResult
+
+	// the number of files skipped because of the filter
+	skipped int
+}
+
+// the result of a single auditor
+type result struct {
+	auditor  string
+	severity auditors.Severity
+	msg      string
+}
+
+// the results of all auditors run on a single ROM
+type romResult struct {
+	filename string
+	hash     string
+	size     int
+	mapper   string
+	spec     string
+	results  []result
+
+	// the controller plugged into the left port
+	controller string
+
+	// the database entry for the ROM. nil if there is no entry
+	db *romEntry
+
+	// the catalogue entry for the ROM. nil unless the catalogue subcommand is
+	// being run
+	catalogue *catalogueEntry
+
+	// image of the last frame generated during the audit
+	thumbnail *image.RGBA
+
+	// the number of snapshots taken for the ROM
+	snapshots int
+}
+
+// auditorIDs returns the IDs of the auditors being run as they are returned by
+// the auditor's ID() function
+func (aud *audit) auditorIDs() []string {
+	var ids []string
+	switch {
+	case aud.catalogue != nil:
+		ids = append(ids, catalogueAuditorID)
+	case aud.specs:
+		ids = append(ids, specsAuditorID)
+	default:
+		for _, key := range aud.auditors {
+			ids = append(ids, auditors.Factory[key]().ID())
+		}
+	}
+
+	// the database check is made in every mode
+	if aud.database != nil {
+		ids = append(ids, databaseAuditorID)
+	}
+	return ids
+}
+
+// usageAuditors lists every auditor and the parameters accepted by each
+// auditor
+func usageAuditors(w io.Writer) {
+	var keys []string
+	for key := range auditors.Factory {
+		if key != auditors.DefaultAuditor {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	fmt.Fprint(w, "\nAuditors: ", allAuditors)
+	for _, key := range keys {
+		fmt.Fprint(w, " ", auditors.Factory[key]().ID())
+	}
+	fmt.Fprintln(w, "")
+
+	fmt.Fprintln(w, "\nAuditor parameters (-a Auditor:name=value,name=value):")
+	for _, key := range keys {
+		audit := auditors.Factory[key]()
+		if cfg, ok := audit.(auditors.Configurable); ok {
+			for _, p := range cfg.Params() {
+				fmt.Fprintf(w, "  %s:%s=%s (%s)\n\t%s\n", audit.ID(), p.Name, p.Default(), p.Type(), p.Help)
+			}
+		}
+	}
+}
+
+// listAuditors prints the description of every auditor grouped by category
+func listAuditors(w io.Writer) {
+	var list []auditors.Audit
+	for key, f := range auditors.Factory {
+		if key != auditors.DefaultAuditor {
+			list = append(list, f())
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		ci := auditors.Describe(list[i]).Category
+		cj := auditors.Describe(list[j]).Category
+		if ci != cj {
+			return ci < cj
+		}
+		return list[i].ID() < list[j].ID()
+	})
+
+	for _, audit := range list {
+		info := auditors.Describe(audit)
+		fmt.Fprintf(w, "%s", audit.ID())
+		if info.Version != "" {
+			fmt.Fprintf(w, " %s", info.Version)
+		}
+		if info.Category != "" {
+			fmt.Fprintf(w, " [%s]", info.Category)
+		}
+		fmt.Fprintln(w, "")
+		if info.Description != "" {
+			fmt.Fprintf(w, "\t%s\n", info.Description)
+		}
+		if cfg, ok := audit.(auditors.Configurable); ok {
+			for _, p := range cfg.Params() {
+				fmt.Fprintf(w, "\t%s=%s (%s) %s\n", p.Name, p.Default(), p.Type(), p.Help)
+			}
+		}
+	}
+}
+
+// exit codes when the fail-on threshold has been met. exit code 1 is used by
+// log.Fatal() for errors that prevent the audit from completing
+func exitCode(worst auditors.Severity) int {
+	switch worst {
+	case auditors.Warning:
+		return 2
+	case auditors.Error:
+		return 3
+	}
+	return 0
+}
+
+// summary writes a count of results by severity to the io.Writer. returns the
+// most severe result found
+func (aud *audit) summary(w io.Writer) auditors.Severity {
+	var counts [auditors.Error + 1]int
+	var worst auditors.Severity
+
+	for _, rom := range aud.results {
+		for _, res := range rom.results {
+			counts[res.severity]++
+			worst = max(worst, res.severity)
+		}
+	}
+
+	fmt.Fprintf(w, "%d ROMs: %d okay, %d warning, %d error, %d files skipped\n", len(aud.results),
+		counts[auditors.Okay], counts[auditors.Warning], counts[auditors.Error], aud.skipped)
+
+	return worst
 }
 
-func (aud *audit) run(pth string) error {
+// an audit of a single ROM waiting to be run by a worker
+type job struct {
+	loader cartridgeloader.Loader
+	rom    *romResult
+	spec   string
+
+	// the multiload numbers of the loads in a multiload ROM. nil if the ROM
+	// is not a multiload ROM
+	loads []int
+
+	// the playback recording for the ROM. nil if there is no recording
+	recording *recording
+}
+
+func (aud *audit) run(ctx context.Context, pth string) error {
 	// check path to roms argument
 	f, err := os.Open(pth)
 	if err != nil {
@@ -56,133 +295,545 @@ func (aud *audit) run(pth string) error {
 	var afs archivefs.Path
 	defer afs.Close()
 
-	auditResult := func(loader cartridgeloader.Loader, msg string) {
-		// cropped filename
-		fn := filepath.Clean(loader.Filename)
-		fn, _ = strings.CutPrefix(fn, prefix)
-		fn, _ = strings.CutPrefix(fn, string(os.PathSeparator))
-
+	columnFilename := func(fn string) string {
 		const filenameColumnWidth = 48
 
 		if len(fn) > filenameColumnWidth {
 			fn = fn[len(fn)-filenameColumnWidth:]
 		}
-		fn = fmt.Sprintf("%s%s", fn, strings.Repeat(" ", filenameColumnWidth-len(fn)))
+		return fmt.Sprintf("%s%s", fn, strings.Repeat(" ", filenameColumnWidth-len(fn)))
+	}
 
-		// print message
-		fmt.Printf("%s\t%s\n", fn, msg)
+	// whether there is more than one result for each ROM
+	showID := (len(aud.auditors) > 1 && !aud.specs && aud.catalogue == nil) || aud.database != nil
+
+	auditResult := func(rom *romResult, res result) {
+		fn := columnFilename(rom.filename)
+
+		// findings are prefixed with their severity
+		msg := res.msg
+		if res.severity != auditors.Okay {
+			msg = fmt.Sprintf("%s: %s", res.severity, msg)
+		}
 
-		// note that the ROM has been audited
-		aud.completed[loader.HashMD5] = append(aud.completed[loader.HashMD5], loader.Name)
+		// print message. the auditor ID is only printed if there is more than
+		// one auditor being run
+		if showID {
+			aud.progress.printf("%s\t%s\t%s\n", fn, res.auditor, msg)
+		} else {
+			aud.progress.printf("%s\t%s\n", fn, msg)
+		}
+
+		rom.results = append(rom.results, res)
 	}
 
-	// auditing process
-	auditf := func(loader cartridgeloader.Loader, audit auditors.Audit) error {
-		// new television with auto-selecting tv protocl
-		tv, err := television.NewTelevision("AUTO")
+	// auditing process. an error is returned if the emulation could not be
+	// prepared or if the context has been cancelled
+	//
+	// the loads argument is the list of multiload numbers for a multiload ROM.
+	// a new instance of the auditor is started every time the ROM requests a
+	// load and the result for each load is printed as soon as the load ends
+	auditf := func(emu *emulation, loader cartridgeloader.Loader, id string, rom *romResult, st startup, loads []int) (result, error) {
+		err := emu.attach(loader, st, true)
 		if err != nil {
-			return err
+			return result{}, err
 		}
-		defer tv.End()
-		tv.SetFPSCap(false)
 
-		// new VCS
-		vcs, err := hardware.NewVCS(environment.MainEmulation, tv, nil, nil)
-		if err != nil {
-			return err
+		// thumbnail is only required for the HTML report
+		if aud.html != "" {
+			thmb := &thumbnail{}
+			emu.tv.AddPixelRenderer(thmb)
+			defer func() {
+				emu.tv.RemovePixelRenderer(thmb)
+				if thmb.img != nil {
+					rom.thumbnail = thmb.img
+				}
+			}()
 		}
 
-		err = vcs.AttachCartridge(loader)
-		if err != nil {
-			return err
+		// the state of the emulation is captured for snapshots for every
+		// instance of the auditor
+		var capture *frameCapture
+		var writes *writeCapture
+		if aud.snapshots != "" {
+			capture = &frameCapture{}
+			emu.tv.AddPixelRenderer(capture)
+			defer emu.tv.RemovePixelRenderer(capture)
+			writes = newWriteCapture()
 		}
 
-		if _, ok := aud.completed[loader.HashMD5]; !ok {
-			vcs.Mem.Cart.Reset()
+		// the current instance of the auditor and whether it is attached to
+		// the emulation
+		var audit auditors.Audit
+		var attached bool
+
+		auditorID := func() string {
+			if audit == nil {
+				return auditors.Factory[id]().ID()
+			}
+			return audit.ID()
+		}
 
-			audit.Initialise(vcs)
+		detach := func() {
+			if attached {
+				emu.detach(audit)
+				attached = false
+			}
+		}
+		defer detach()
+
+		// start a new instance of the auditor
+		start := func() error {
+			a, err := auditors.New(id, aud.params[id])
+			if err != nil {
+				return err
+			}
+			audit = a
+
+			// auditors that support snapshots are given a function that saves
+			// the state of the emulation when called
+			if s, ok := a.(auditors.Snapshotter); ok && capture != nil {
+				var taken int
+				s.SetSnapshot(func(reason string) {
+					if taken >= snapshotLimit {
+						return
+					}
+					taken++
+					err := aud.snapshot(emu, capture, writes, rom, a.ID(), reason)
+					if err != nil {
+						aud.progress.printf("%s\t%s\t%v\n", rom.filename, a.ID(), err)
+					}
+				})
+			}
+
+			// auditors that write files name them after the hash of the ROM
+			if h, ok := a.(auditors.Hasher); ok {
+				h.SetHash(rom.hash)
+			}
+
+			// an audit with a recording runs until the end of the recording
+			if emu.recording != nil {
+				err := unlimitFrames(a)
+				if err != nil {
+					return err
+				}
+			}
+
+			attached = true
+			return a.Initialise(emu.vcs)
+		}
+
+		// finish the current instance of the auditor once the check has ended
+		finish := func() result {
+			defer detach()
+
+			res := result{auditor: audit.ID()}
+
+			var msg strings.Builder
+			err := audit.Finalise(&msg)
+			res.severity = auditors.SeverityOf(err)
+			if res.severity == auditors.Okay {
+				if msg.Len() == 0 {
+					res.msg = "okay"
+				} else {
+					res.msg = msg.String()
+				}
+			} else {
+				res.msg = err.Error()
+			}
+
+			return res
+		}
+
+		var ml *multiload
+		if loads != nil {
+			ml = newMultiload(loads)
+		}
+
+		err = start()
+		if err == nil {
+			err = emu.vcs.Run(func() (govern.State, error) {
+				emu.cycles += emu.vcs.CPU.LastResult.Cycles
+				if err := ctx.Err(); err != nil {
+					return govern.Ending, err
+				}
+				if writes != nil {
+					writes.step(emu.vcs)
+				}
+
+				// the check ends with the recording
+				ended, err := emu.playbackEnded()
+				if err != nil {
+					return govern.Ending, err
+				}
+				if ended {
+					return govern.Ending, auditors.CheckEnded
+				}
+
+				// a load request ends the audit of the current load. the
+				// audit continues with a new instance of the auditor for
+				// the requested load
+				if ml != nil {
+					if n, ok := ml.requested(emu.vcs); ok {
+						ml.ended(finish())
+						ml.current = n
+						if !ml.exists(n) {
+							return govern.Ending, fmt.Errorf("load %d requested but not in the binary", n)
+						}
+						if err := start(); err != nil {
+							return govern.Ending, err
+						}
+					}
+				}
 
-			err := vcs.Run(func() (govern.State, error) {
 				if err := audit.Check(); err != nil {
 					return govern.Ending, err
 				}
 				return govern.Running, nil
 			})
+			emu.frames += emu.tv.GetCoords().Frame
+		}
 
-			if errors.Is(err, auditors.CheckEnded) {
-				var msg strings.Builder
-				err = audit.Finalise(&msg)
-				if errors.Is(err, auditors.FinalisedOk) {
-					var s string
-					if msg.Len() == 0 {
-						s = "okay"
-					} else {
-						s = msg.String()
-					}
-					auditResult(loader, s)
-				} else {
-					auditResult(loader, err.Error())
-				}
-			} else {
-				auditResult(loader, err.Error())
+		var res result
+		if errors.Is(err, auditors.CheckEnded) {
+			res = finish()
+		} else if ctx.Err() != nil {
+			return result{}, ctx.Err()
+		} else {
+			res = result{auditor: auditorID(), severity: auditors.Error, msg: err.Error()}
+		}
+
+		// the results of each load are combined into a single result
+		if ml != nil {
+			ml.ended(res)
+			res = ml.result(res.auditor)
+		}
+
+		rom.mapper = emu.vcs.Mem.Cart.ID()
+		rom.spec = emu.tv.GetFrameInfo().Spec.ID
+		rom.controller = string(emu.vcs.RIOT.Ports.PeripheralID(plugging.PortLeft))
+
+		return res, nil
+	}
+
+	// run a new instance of the auditor with the startup state. errors are
+	// reported as a result of the auditor
+	runAuditor := func(emu *emulation, loader cartridgeloader.Loader, id string, rom *romResult, st startup, loads []int) result {
+		res, err := auditf(emu, loader, id, rom, st, loads)
+		if err != nil {
+			return result{auditor: auditors.Factory[id]().ID(), severity: auditors.Error, msg: err.Error()}
+		}
+		return res
+	}
+
+	// run every auditor on the ROM in the job. results of audits interrupted
+	// by the cancellation of the context are discarded
+	runJob := func(emus emulations, j job) {
+		// the database is checked after the audit because it is compared with
+		// what was observed during the audit
+		defer func() {
+			if aud.database != nil && ctx.Err() == nil {
+				auditResult(j.rom, aud.checkDatabase(j.rom))
 			}
+		}()
 
+		// the catalogue subcommand and the specs mode replace the normal
+		// auditors
+		if aud.catalogue != nil {
+			res := aud.catalogueROM(ctx, emus, j.loader, j.rom, j.spec)
+			if ctx.Err() == nil {
+				auditResult(j.rom, res)
+			}
+			return
+		}
+		if aud.specs {
+			res := aud.compareSpecs(ctx, emus, j.loader, j.rom)
+			if ctx.Err() == nil {
+				auditResult(j.rom, res)
+			}
+			return
 		}
 
-		return nil
+		emu, err := emus.get(j.spec)
+		if err != nil {
+			auditResult(j.rom, result{auditor: loaderAuditorID, severity: auditors.Error, msg: err.Error()})
+			return
+		}
+
+		// ROMs with a playback recording are audited with a new emulation
+		// every time because the recording can't be removed from an emulation
+		// once it has been attached
+		runOnce := func(id string, st startup) result {
+			if j.recording == nil {
+				return runAuditor(emu, j.loader, id, j.rom, st, j.loads)
+			}
+
+			rec, err := newEmulation(j.spec)
+			if err != nil {
+				return result{auditor: auditors.Factory[id]().ID(), severity: auditors.Error, msg: err.Error()}
+			}
+			defer rec.vcs.End()
+			rec.recording = j.recording
+
+			res := runAuditor(rec, j.loader, id, j.rom, st, j.loads)
+			emu.frames += rec.frames
+			emu.cycles += rec.cycles
+			return res
+		}
+
+		// run the auditor. in the stability mode the auditor is run once for
+		// each seed
+		runSeeds := func(id string) result {
+			if aud.stability == 0 {
+				return runOnce(id, aud.startup)
+			}
+
+			var seeds []int64
+			var results []result
+			for i := range aud.stability {
+				seed := aud.startup.seed + int64(i)
+				seeds = append(seeds, seed)
+				results = append(results, runOnce(id, aud.startup.withSeed(seed)))
+				if ctx.Err() != nil {
+					return result{}
+				}
+			}
+			return stability(seeds, results)
+		}
+
+		// create new auditor instances. validity of auditor ids should have
+		// been checked already
+		for _, id := range aud.auditors {
+			res := runSeeds(id)
+			if ctx.Err() != nil {
+				return
+			}
+			auditResult(j.rom, res)
+		}
 	}
 
-	// counts active audit goroutines
+	// worker pool. each worker has its own emulations which are reused for
+	// every ROM that the worker audits. the workers are started before the
+	// walk so that auditing begins as soon as the first ROM is found
+	queue := make(chan job)
+
 	var wg sync.WaitGroup
+	for worker := range aud.workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
 
-	// number of emulation slots depending on concurrency argument
-	var slots chan bool
-	if aud.concurrent {
-		slots = make(chan bool, runtime.NumCPU())
-	} else {
-		slots = make(chan bool, 1)
+			emus := make(emulations)
+			defer emus.end()
+
+			for j := range queue {
+				startTime := time.Now()
+				startFrames, startCycles := emus.counts()
+
+				runJob(emus, j)
+				if ctx.Err() != nil {
+					continue
+				}
+				aud.progress.completed()
+
+				if aud.bench != nil {
+					frames, cycles := emus.counts()
+					aud.bench.add(benchSample{
+						worker:   worker,
+						mapper:   j.rom.mapper,
+						duration: time.Since(startTime),
+						frames:   frames - startFrames,
+						cycles:   cycles - startCycles,
+					})
+				}
+			}
+		}()
 	}
 
-	var walkf func(pth string, depth int) error
-	walkf = func(pth string, depth int) error {
+	aud.progress.begin()
+	defer aud.progress.end()
+
+	if aud.bench != nil {
+		startTime := time.Now()
+		defer func() {
+			aud.bench.wall += time.Since(startTime)
+		}()
+	}
+
+	// the queue is closed once the walk has ended and the workers finish once
+	// the queue is empty. the deferred functions run in reverse order so the
+	// workers have finished before the progress line is removed
+	defer wg.Wait()
+	defer close(queue)
+
+	// jobs are sent to the worker pool as they are found by the walk. the
+	// walk waits for a worker to be ready so that the data for only a small
+	// number of ROMs is held in memory at any one time
+	enqueue := func(j job) {
+		aud.progress.queued()
+		select {
+		case queue <- j:
+		case <-ctx.Done():
+		}
+	}
+
+	// cropped filename
+	crop := func(name string) string {
+		fn := filepath.Clean(name)
+		fn, _ = strings.CutPrefix(fn, prefix)
+		fn, _ = strings.CutPrefix(fn, string(os.PathSeparator))
+		return fn
+	}
+
+	// skipped files are noted but are otherwise ignored
+	skip := func(fn string, reason string) {
+		aud.progress.printf("%s\tskipped: %s\n", columnFilename(fn), reason)
+		aud.skipped++
+	}
+
+	// the data from a single file. the name is the full name of the file and
+	// is used to identify the ROM. archive files are expanded and every file
+	// inside the archive is added
+	var addData func(name string, data []byte, archiveDepth int)
+	addData = func(name string, data []byte, archiveDepth int) {
+		fn := crop(name)
+
+		if typ := identifyArchive(name, data); typ != notArchive {
+			if archiveDepth >= maxArchiveDepth {
+				skip(fn, "archive is nested too deeply")
+				return
+			}
+
+			entries, err := expandArchive(typ, data)
+			if err != nil {
+				rom := &romResult{
+					filename: fn,
+					hash:     fmt.Sprintf("%x", md5.Sum(data)),
+				}
+				aud.results = append(aud.results, rom)
+				auditResult(rom, result{auditor: loaderAuditorID, severity: auditors.Error, msg: err.Error()})
+				return
+			}
+
+			for _, e := range entries {
+				addData(archiveName(name, e.name), e.data, archiveDepth+1)
+			}
+			return
+		}
+
+		if reason := aud.filter.skip(fn, len(data)); reason != "" {
+			skip(fn, reason)
+			return
+		}
+
+		hash := fmt.Sprintf("%x", md5.Sum(data))
+
+		// tv specification and mapper for this ROM
+		spec, mapper := aud.settings(hash, fn)
+
+		// a file that can't be loaded is an error for that ROM but not for
+		// the audit as a whole
+		loader, err := cartridgeloader.NewLoaderFromData(name, data, mapper, "AUTO", nil)
+		if err != nil {
+			rom := &romResult{
+				filename: fn,
+				hash:     hash,
+			}
+			aud.results = append(aud.results, rom)
+			auditResult(rom, result{auditor: loaderAuditorID, severity: auditors.Error, msg: err.Error()})
+			return
+		}
+
+		// note that the ROM has been audited. we don't audit duplicate ROMs
+		// but we do keep a note of the names
+		if _, ok := aud.completed[loader.HashMD5]; ok {
+			aud.completed[loader.HashMD5] = append(aud.completed[loader.HashMD5], loader.Name)
+			return
+		}
+		aud.completed[loader.HashMD5] = []string{loader.Name}
+
+		// a ROM with a recording is always audited with the specification the
+		// recording was made with
+		rec := aud.recs.match(loader)
+		if rec != nil {
+			spec = rec.spec
+		}
+
+		rom := &romResult{
+			filename: fn,
+			hash:     loader.HashMD5,
+			size:     len(data),
+		}
+		aud.results = append(aud.results, rom)
+
+		enqueue(job{
+			loader:    loader,
+			rom:       rom,
+			spec:      spec,
+			loads:     multiloads(data, mapper),
+			recording: rec,
+		})
+	}
+
+	// the archiveRoot argument is the path of the archive file that archivefs
+	// has opened. it is empty if the path is not inside an archive
+	var walkf func(pth string, depth int, archiveRoot string) error
+	walkf = func(pth string, depth int, archiveRoot string) error {
 		// prevent recursion unless it's been activated
 		if !aud.recurse && depth > 1 {
 			return nil
 		}
 
+		// stop walking if the audit has been cancelled
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		err := afs.Set(pth, false)
 		if err != nil {
 			return err
 		}
 
+		// files inside an archive are named with the archive separator
+		name := afs.String()
+		if afs.InArchive() {
+			if archiveRoot == "" {
+				archiveRoot = name
+			}
+			if name != archiveRoot {
+				name = archiveName(archiveRoot, strings.TrimPrefix(name, archiveRoot))
+			}
+		}
+
 		if !afs.IsDir() {
+			fn := crop(name)
+
 			r, n, err := afs.Open()
 			if err != nil {
 				return err
 			}
+			if c, ok := r.(io.Closer); ok {
+				defer c.Close()
+			}
 
-			data := make([]byte, n)
-			_, err = r.Read(data)
-			if err != nil {
-				return err
+			// skip files before reading them if possible. archive files are
+			// never skipped at this point
+			if !isArchiveName(fn) {
+				if reason := aud.filter.skip(fn, n); reason != "" {
+					skip(fn, reason)
+					return nil
+				}
 			}
 
-			loader, err := cartridgeloader.NewLoaderFromData(afs.String(), data, "AUTO", "AUTO", nil)
+			// read the entire file. a single call to Read() is not guaranteed
+			// to fill the buffer
+			data := make([]byte, n)
+			_, err = io.ReadFull(r, data)
 			if err != nil {
-				return err
+				return fmt.Errorf("%s: %w", fn, err)
 			}
 
-			// create new auditor instance. validity of auditor id should have
-			// been checked already
-			audit := auditors.Factory[aud.auditor]()
-
-			wg.Add(1)
-			slots <- true
-			go func() {
-				_ = auditf(loader, audit)
-				<-slots
-				wg.Done()
-			}()
+			addData(name, data, 0)
 			return nil
 		}
 
@@ -192,7 +843,7 @@ func (aud *audit) run(pth string) error {
 		}
 
 		for _, l := range lst {
-			err = walkf(filepath.Join(pth, l.Name), depth+1)
+			err = walkf(filepath.Join(pth, l.Name), depth+1, archiveRoot)
 			if err != nil {
 				return err
 			}
@@ -201,14 +852,16 @@ func (aud *audit) run(pth string) error {
 		return nil
 	}
 
-	err = walkf(pth, 0)
+	err = walkf(pth, 0, "")
+	aud.progress.walked()
 	if err != nil {
+		// cancellation is not an error. the results so far will be reported
+		if ctx.Err() != nil {
+			return nil
+		}
 		return err
 	}
 
-	// wait until all audit goroutines are finished
-	wg.Wait()
-
 	return nil
 }
 
@@ -218,45 +871,287 @@ func main() {
 
 	aud := &audit{
 		completed: make(map[string][]string),
+		params:    make(map[string]string),
+	}
+
+	// command line arguments not including the program name
+	args := os.Args[1:]
+
+	// subcommands are specified by the first argument
+	if len(args) > 0 {
+		switch args[0] {
+		case "diff":
+			err := diff(os.Stdout, args[1:])
+			if err != nil && !errors.Is(err, flag.ErrHelp) {
+				log.Fatal(err)
+			}
+			return
+		case "catalogue":
+			aud.catalogue = &catalogue{}
+			args = args[1:]
+		}
 	}
 
 	// command line options
 	flgs := flag.NewFlagSet("Gopher2600-Audit", flag.ContinueOnError)
 
+	// options for the catalogue subcommand
+	if aud.catalogue != nil {
+		flgs.StringVar(&aud.catalogue.output, "o", "", "catalogue output file")
+		flgs.StringVar(&aud.catalogue.format, "format", "", "catalogue format: json|sqlite. default is decided by the extension of the output file")
+		flgs.StringVar(&aud.catalogue.thumbs, "thumbs", "", "directory in which to save a PNG of the first frame of each ROM")
+	}
+
 	flgs.BoolVar(&aud.recurse, "r", false, "recurse into directories")
-	flgs.BoolVar(&aud.concurrent, "c", false, fmt.Sprintf("run audits concurrently (max: %d)", runtime.NumCPU()))
-	flgs.StringVar(&aud.auditor, "a", auditors.Factory[auditors.DefaultAuditor]().ID(), "which auditor to run")
+	flgs.BoolVar(&aud.concurrent, "c", false, fmt.Sprintf("run audits concurrently. same as -j %d", runtime.NumCPU()))
+	flgs.IntVar(&aud.workers, "j", 0, "number of audits to run concurrently")
+	flgs.BoolVar(&aud.progress.enabled, "progress", false, "show progress and estimated time remaining on stderr")
+	flgs.StringVar(&aud.auditor, "a", auditors.Factory[auditors.DefaultAuditor]().ID(), "which auditor to run. parameters can follow the auditor, eg. ShortVsync:min=3,frames=300")
+	flgs.StringVar(&aud.rules, "rules", "", "JSON file of rule based auditors to load")
+	flgs.StringVar(&aud.script, "script", "", "Lua script auditor to load")
+	flgs.StringVar(&aud.html, "html", "", "run every auditor and write an HTML report to the named file")
+	flgs.StringVar(&aud.json, "json", "", "save the results to the named file for use with the diff subcommand")
+	flgs.StringVar(&aud.failOn, "fail-on", "none", "exit with a non-zero code if any result is at least this severe: none|warning|error")
+	flgs.StringVar(&aud.tv, "tv", "AUTO", "tv specification: NTSC|PAL|PAL-M|SECAM|AUTO")
+	flgs.StringVar(&aud.mapper, "mapper", "AUTO", "cartridge mapper to use instead of auto-detection")
+	flgs.BoolVar(&aud.specs, "specs", false, fmt.Sprintf("instead of running auditors, run each ROM under every tv specification (%s) and compare", strings.Join(specList, ", ")))
+	flgs.StringVar(&aud.include, "include", "", "comma separated glob patterns of files to audit")
+	flgs.StringVar(&aud.exclude, "exclude", "", "comma separated glob patterns of files to skip")
+	flgs.StringVar(&aud.ext, "ext", "", "comma separated file extensions to audit. default is every extension recognised by the cartridge loader. * for any extension")
+	flgs.StringVar(&aud.minSize, "min-size", "", "skip files smaller than this size. K and M suffixes are allowed")
+	flgs.StringVar(&aud.maxSize, "max-size", "", "skip files larger than this size. K and M suffixes are allowed")
+	flgs.StringVar(&aud.randomise, "randomise", randomiseNone, "randomise the VCS at startup: none|ram|all")
+	flgs.Int64Var(&aud.seed, "seed", 0, "seed used to randomise the VCS at startup")
+	flgs.IntVar(&aud.stability, "stability", 0, "run each auditor under this many seeds and report auditors whose verdict changes")
+	benchFlag := flgs.Bool("bench", false, "report emulated frames, CPU cycles and ROMs per unit of time for each worker and mapper")
+	flgs.BoolVar(&aud.profile, "profile", false, "write cpu.profile and mem.profile to the current directory")
+	flgs.StringVar(&aud.db, "db", "", "ROM database (Stella properties or XML DAT file) used to identify ROMs and check expectations")
+	flgs.StringVar(&aud.override, "overrides", "", "JSON file of tv and mapper overrides for specific ROMs")
+	flgs.StringVar(&aud.snapshots, "snapshots", "", "directory in which to save the emulation state and frame when an auditor detects a finding")
+	flgs.StringVar(&aud.recordings, "recordings", "", "directory of Gopher2600 playback recordings. a ROM with a recording is audited while the recording is replayed")
+	listFlag := flgs.Bool("list", false, "list every auditor with its description and parameters")
 
 	// parse command line
-	args := os.Args[1:]
 	err := flgs.Parse(args)
 	if err != nil {
 		if errors.Is(err, flag.ErrHelp) {
-			fmt.Print("\nAuditors: ")
-			for key, aud := range auditors.Factory {
-				if key != auditors.DefaultAuditor {
-					fmt.Print(aud().ID(), " ")
-				}
-			}
-			fmt.Println("")
+			usageAuditors(os.Stdout)
 			return
 		}
 		log.Fatal(err)
 	}
 
-	// check that selected auditor is valid
-	n := auditors.NormaliseID(aud.auditor)
-	if _, ok := auditors.Factory[n]; !ok {
-		log.Fatalf("*** invalid auditor: %s", aud.auditor)
+	// load rule based and scripted auditors before checking the validity of
+	// the selected auditor. the selected auditor may be one of these
+	if aud.rules != "" {
+		err := auditors.LoadRules(aud.rules)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if aud.script != "" {
+		err := auditors.LoadScript(aud.script)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *listFlag {
+		listAuditors(os.Stdout)
+		return
+	}
+
+	// check tv specification
+	var ok bool
+	aud.tv, ok = specification.NormaliseReqSpecID(aud.tv)
+	if !ok {
+		log.Fatalf("*** unsupported tv specification: %s", aud.tv)
+	}
+
+	// check randomisation options
+	aud.startup.randomise, err = parseRandomise(aud.randomise)
+	if err != nil {
+		log.Fatal(err)
+	}
+	aud.startup.seed = aud.seed
+	if aud.stability < 0 {
+		log.Fatalf("*** invalid stability value: %d", aud.stability)
+	}
+	if aud.stability > 0 && aud.startup.randomise == randomiseNone {
+		log.Fatalf("*** stability mode requires randomise option of %s or %s", randomiseRAM, randomiseAll)
+	}
+
+	if aud.override != "" {
+		aud.overrides, err = loadOverrides(aud.override)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// check catalogue options
+	if aud.catalogue != nil {
+		if aud.catalogue.output == "" {
+			log.Fatal("*** catalogue requires an output file")
+		}
+		aud.catalogue.format, err = catalogueFormat(aud.catalogue.format, aud.catalogue.output)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if aud.catalogue.thumbs != "" {
+			err = os.MkdirAll(aud.catalogue.thumbs, 0755)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	if aud.db != "" {
+		aud.database, err = loadDatabase(aud.db)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if aud.recordings != "" {
+		// the frame digests in a recording can only match an emulation that
+		// starts in the same state as the emulation the recording was made with
+		if aud.startup.randomise != randomiseNone || aud.stability > 0 {
+			log.Fatal("*** recordings cannot be used with the randomise or stability options")
+		}
+
+		aud.recs, err = loadRecordings(aud.recordings)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	aud.filter, err = newFilter(aud.include, aud.exclude, aud.ext, aud.minSize, aud.maxSize)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// check failure threshold
+	var failOn auditors.Severity
+	if aud.failOn != "none" {
+		failOn, err = auditors.ParseSeverity(aud.failOn)
+		if err != nil || failOn == auditors.Okay {
+			log.Fatalf("*** invalid fail-on severity: %s", aud.failOn)
+		}
+	}
+
+	// check that selected auditor is valid. the auditor can be followed by a
+	// list of parameters
+	id, params, _ := strings.Cut(aud.auditor, ":")
+	n := auditors.NormaliseID(id)
+	if n == allAuditors {
+		if params != "" {
+			log.Fatalf("*** parameters cannot be given for the %s auditor", allAuditors)
+		}
+	} else {
+		if _, err := auditors.New(n, params); err != nil {
+			log.Fatalf("*** %v", err)
+		}
+		aud.params[n] = params
 	}
 	aud.auditor = n
 
+	// the HTML report includes the result of every auditor. every auditor can
+	// also be requested with the special ALL auditor
+	if aud.html != "" || aud.auditor == allAuditors {
+		for key := range auditors.Factory {
+			if key != auditors.DefaultAuditor {
+				aud.auditors = append(aud.auditors, key)
+			}
+		}
+		sort.Strings(aud.auditors)
+	} else {
+		aud.auditors = []string{aud.auditor}
+	}
+
+	// number of workers
+	if aud.workers < 0 {
+		log.Fatalf("*** invalid number of workers: %d", aud.workers)
+	}
+	if aud.workers == 0 {
+		if aud.concurrent {
+			aud.workers = runtime.NumCPU()
+		} else {
+			aud.workers = 1
+		}
+	}
+
+	// the audit can be interrupted with ctrl-c. the results so far are still
+	// reported
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if *benchFlag {
+		aud.bench = &benchmark{}
+	}
+
 	// treat all remaining arguments as paths
-	for _, pth := range flgs.Args() {
-		pth = filepath.Clean(pth)
-		err := aud.run(pth)
+	audits := func() {
+		for _, pth := range flgs.Args() {
+			pth = filepath.Clean(pth)
+			err := aud.run(ctx, pth)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+	}
+
+	if aud.profile {
+		err := profile(audits)
+		if err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		audits()
+	}
+
+	// ROMs that were waiting to be audited when the audit was interrupted
+	// have no results and are removed
+	interrupted := ctx.Err() != nil
+	if interrupted {
+		aud.results = slices.DeleteFunc(aud.results, func(rom *romResult) bool {
+			return len(rom.results) == 0
+		})
+	}
+
+	if aud.html != "" {
+		err := aud.writeReport(aud.html)
 		if err != nil {
 			log.Fatal(err)
 		}
 	}
+
+	if aud.catalogue != nil {
+		err := aud.writeCatalogue()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if aud.json != "" {
+		err := aud.writeRun(aud.json)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	aud.recs.report(os.Stdout)
+	worst := aud.summary(os.Stdout)
+	if aud.bench != nil {
+		fmt.Println("")
+		aud.bench.report(os.Stdout)
+	}
+	if interrupted {
+		log.Fatal("*** audit interrupted")
+	}
+	if failOn != auditors.Okay && worst >= failOn {
+		os.Exit(exitCode(worst))
+	}
 }
@@ -0,0 +1,252 @@
+package auditors
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/coprocessor"
+	"github.com/jetsetilly/gopher2600/hardware"
+	armcpu "github.com/jetsetilly/gopher2600/hardware/memory/cartridge/arm"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cartridge/mapper"
+	"github.com/jetsetilly/gopher2600/hardware/television/frameinfo"
+)
+
+// the clock speed of the 6507 in MHz for each TV specification. the clock is
+// the colour subcarrier frequency of the specification divided by three
+const (
+	cpuClockNTSC  = 1.193182
+	cpuClockPAL   = 1.182298
+	cpuClockPALM  = 1.191870
+	cpuClockSECAM = 1.187500
+)
+
+// arm notes the activity of the ARM coprocessor in cartridges that have one.
+// ROMs without a coprocessor are reported as okay
+//
+// the time taken by each execution of the ARM program is measured with the
+// cycle counts reported by the coprocessor at the end of the execution. the
+// time is compared with the 6507 time between the start of the execution and
+// the start of the next execution. a kernel that is still running when it is
+// next called has overrun
+type arm struct {
+	vcs     *hardware.VCS
+	frameCt int
+	bus     mapper.CartCoProcBus
+
+	// the cartridge mapper and the coprocessor reported by the cartridge
+	mapper    string
+	processor string
+
+	// the number of 6507 cycles since the start of the audit
+	cpuCycles int
+
+	// the 6507 cycle at which the current or most recent ARM execution
+	// started and the time the execution took in microseconds. started is
+	// false until the first execution has finished
+	started    bool
+	startCycle int
+	armTime    float64
+
+	// the ARM ran in immediate mode, in which cycles are not counted
+	immediate bool
+
+	// number of ARM cycles used in the current frame
+	cycles int
+
+	// the most and the total number of ARM cycles used in a stable frame.
+	// along with the number of stable frames measured
+	maxCycles   int
+	totalCycles int
+	measured    int
+
+	// number of kernel calls and the number of calls in which the ARM took
+	// longer than the 6507 time available before the next call
+	calls    int
+	overruns int
+
+	// the errors reported by the coprocessor and the number of times each one
+	// was reported
+	faults   map[string]int
+	faultIDs []string
+
+	// parameters
+	frames int
+}
+
+// ID implements the Audit interface
+func (audit *arm) ID() string {
+	return "ARM"
+}
+
+// Info implements the Describer interface
+func (audit *arm) Info() Info {
+	return Info{
+		Description: "ARM coprocessor activity, overruns and faults",
+		Version:     "1.0",
+		Category:    "cartridge",
+	}
+}
+
+// Params implements the Configurable interface
+func (audit *arm) Params() []Param {
+	return []Param{
+		{Name: framesParam, Help: "number of frames to run the ROM for", Value: &audit.frames},
+	}
+}
+
+// Initialise implements the Audit interface
+func (audit *arm) Initialise(vcs *hardware.VCS) error {
+	audit.vcs = vcs
+	audit.vcs.TV.AddFrameTrigger(audit)
+	audit.faults = make(map[string]int)
+
+	audit.mapper = vcs.Mem.Cart.ID()
+	audit.bus = vcs.Mem.Cart.GetCoProcBus()
+	if audit.bus != nil {
+		audit.bus.SetYieldHook(audit)
+		if coproc := audit.bus.GetCoProc(); coproc != nil {
+			audit.processor = coproc.ProcessorID()
+			coproc.SetDisassembler(audit)
+		}
+	}
+
+	return nil
+}
+
+// Check implements the Audit interface
+func (audit *arm) Check() error {
+	if audit.frameCt > audit.frames {
+		return CheckEnded
+	}
+
+	// nothing to measure if there is no coprocessor
+	if audit.bus == nil {
+		return CheckEnded
+	}
+
+	audit.cpuCycles += audit.vcs.CPU.LastResult.Cycles
+
+	return nil
+}
+
+// Finalise implements the Audit interface
+func (audit *arm) Finalise(msg *strings.Builder) error {
+	if audit.bus == nil {
+		msg.WriteString(fmt.Sprintf("%s has no coprocessor", audit.mapper))
+		return FinalisedOk
+	}
+
+	summary := fmt.Sprintf("%s (%s)", audit.mapper, audit.processor)
+	if audit.immediate {
+		summary = fmt.Sprintf("%s | immediate mode, ARM cycles not counted", summary)
+	} else if audit.measured > 0 {
+		summary = fmt.Sprintf("%s | %d ARM cycles per frame (max %d) at %.0fMHz", summary,
+			audit.totalCycles/audit.measured, audit.maxCycles, audit.armClock())
+	}
+
+	var findings []string
+	if audit.overruns > 0 {
+		findings = append(findings, fmt.Sprintf("ARM overruns the time between kernel calls in %d of %d calls", audit.overruns, audit.calls))
+	}
+	for _, id := range audit.faultIDs {
+		findings = append(findings, fmt.Sprintf("%s (%d times)", id, audit.faults[id]))
+	}
+
+	if len(findings) > 0 {
+		return Warningf("%s | %s", strings.Join(findings, " | "), summary)
+	}
+
+	msg.WriteString(summary)
+	return FinalisedOk
+}
+
+// CartYield implements the coprocessor.CartYieldHook interface
+func (audit *arm) CartYield(yield coprocessor.CoProcYield) coprocessor.YieldHookResponse {
+	switch yield.Type {
+	case coprocessor.YieldMemoryAccessError, coprocessor.YieldExecutionError,
+		coprocessor.YieldUnimplementedFeature, coprocessor.YieldUndefinedBehaviour:
+		var id string
+		if yield.Error != nil {
+			id = yield.Error.Error()
+		} else {
+			id = fmt.Sprintf("%v", yield.Type)
+		}
+		if _, ok := audit.faults[id]; !ok {
+			audit.faultIDs = append(audit.faultIDs, id)
+		}
+		audit.faults[id]++
+	}
+
+	// the audit always continues. faults are reported by Finalise()
+	return coprocessor.YieldHookContinue
+}
+
+// armClock returns the clock speed of the ARM in MHz
+func (audit *arm) armClock() float64 {
+	return audit.vcs.Env.Prefs.ARM.Clock.Get().(float64)
+}
+
+// cpuClock returns the clock speed of the 6507 in MHz. PAL60 is a PAL console
+// generating a 60Hz signal and so runs at the PAL clock speed
+func (audit *arm) cpuClock() float64 {
+	switch audit.vcs.TV.GetFrameInfo().Spec.ID {
+	case "PAL", "PAL60":
+		return cpuClockPAL
+	case "PAL-M":
+		return cpuClockPALM
+	case "SECAM":
+		return cpuClockSECAM
+	}
+	return cpuClockNTSC
+}
+
+// Start implements the coprocessor.CartCoProcDisassembler interface. it is
+// called when the ARM program is started by the 6507
+func (audit *arm) Start() {
+	// the time available to the previous execution is the 6507 time between
+	// the start of the previous execution and the start of this one
+	if audit.started && !audit.immediate {
+		available := float64(audit.cpuCycles-audit.startCycle) / audit.cpuClock()
+		audit.calls++
+		if audit.armTime > available {
+			audit.overruns++
+		}
+	}
+	audit.startCycle = audit.cpuCycles
+}
+
+// Step implements the coprocessor.CartCoProcDisassembler interface
+func (audit *arm) Step(_ coprocessor.CartCoProcDisasmEntry) {
+}
+
+// End implements the coprocessor.CartCoProcDisassembler interface. it is
+// called with the cycle counts of the execution when the ARM program ends
+func (audit *arm) End(summary coprocessor.CartCoProcDisasmSummary) {
+	s, ok := summary.(armcpu.DisasmSummary)
+	if !ok {
+		return
+	}
+	if s.ImmediateMode {
+		audit.immediate = true
+		return
+	}
+
+	cycles := s.N + s.I + s.S
+	audit.cycles += cycles
+	audit.armTime = float64(cycles) / audit.armClock()
+	audit.started = true
+}
+
+// NewFrame implements the television.FrameTrigger() interface
+func (audit *arm) NewFrame(frameInfo frameinfo.Current) error {
+	audit.frameCt++
+
+	if frameInfo.Stable && audit.cycles > 0 {
+		audit.measured++
+		audit.totalCycles += audit.cycles
+		audit.maxCycles = max(audit.maxCycles, audit.cycles)
+	}
+	audit.cycles = 0
+
+	return nil
+}
@@ -1,12 +1,20 @@
 package auditors
 
 import (
+	"errors"
 	"fmt"
+	"path/filepath"
 	"strings"
 
+	"github.com/jetsetilly/gopher2600/environment"
 	"github.com/jetsetilly/gopher2600/hardware"
 )
 
+// Emulation is the label that should be given to the VCS instances used for
+// auditing. Some peripherals, the SaveKey for example, load and save files on
+// disk when they are part of the main emulation
+const Emulation environment.Label = "audit"
+
 type Audit interface {
 	ID() string
 	Initialise(vcs *hardware.VCS) error
@@ -14,6 +22,40 @@ type Audit interface {
 	Finalise(msg *strings.Builder) error
 }
 
+// Detacher is implemented by auditors that change the emulation in ways other
+// than adding themselves to the television. Detach() is called once the audit
+// has ended, whether or not Finalise() was called, and must undo the changes
+// made by Initialise()
+type Detacher interface {
+	Detach() error
+}
+
+// Hasher is implemented by auditors that write files named after the ROM.
+// SetHash() is called before Initialise() with the hash that identifies the
+// ROM in the audit results
+type Hasher interface {
+	SetHash(hash string)
+}
+
+// romHash can be embedded in an auditor to implement the Hasher interface
+type romHash struct {
+	hash string
+}
+
+// SetHash implements the Hasher interface
+func (h *romHash) SetHash(hash string) {
+	h.hash = hash
+}
+
+// filename returns the name of a file in the directory named after the hash
+// of the ROM with the extension
+func (h *romHash) filename(dir string, ext string) (string, error) {
+	if h.hash == "" {
+		return "", fmt.Errorf("no hash has been set for the ROM")
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s.%s", h.hash, ext)), nil
+}
+
 // sentinal errors
 var (
 	// returned by Check() function
@@ -23,30 +65,155 @@ var (
 	FinalisedOk = fmt.Errorf("finalised okay")
 )
 
+// Severity of the result of an audit
+type Severity int
+
+// List of valid Severity values
+const (
+	Okay Severity = iota
+	Warning
+	Error
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Okay:
+		return "okay"
+	case Warning:
+		return "warning"
+	case Error:
+		return "error"
+	}
+	return "unknown"
+}
+
+// ParseSeverity converts the string representation of a Severity
+func ParseSeverity(s string) (Severity, error) {
+	switch strings.ToLower(s) {
+	case "okay", "ok":
+		return Okay, nil
+	case "warning":
+		return Warning, nil
+	case "error":
+		return Error, nil
+	}
+	return Okay, fmt.Errorf("unrecognised severity: %s", s)
+}
+
+// Finding can be returned by the Finalise() function to indicate the severity
+// of what the auditor has found. Any other error returned by Finalise() (with
+// the exception of FinalisedOk) is treated as having a severity of Error
+type Finding struct {
+	Severity Severity
+	Msg      string
+}
+
+// Error implements the error interface
+func (f Finding) Error() string {
+	return f.Msg
+}
+
+// Warningf creates a Finding with a severity of Warning
+func Warningf(format string, a ...any) error {
+	return Finding{
+		Severity: Warning,
+		Msg:      fmt.Sprintf(format, a...),
+	}
+}
+
+// SeverityOf returns the severity of an error returned by the Finalise() function
+func SeverityOf(err error) Severity {
+	if err == nil || errors.Is(err, FinalisedOk) {
+		return Okay
+	}
+	var f Finding
+	if errors.As(err, &f) {
+		return f.Severity
+	}
+	return Error
+}
+
 func NormaliseID(id string) string {
 	return strings.ToUpper(id)
 }
 
-// Factory is used to create an auditor instance by name
-var Factory map[string]func() Audit
+// Factory is used to create an auditor instance by name. Auditors are added to
+// the Factory with Register()
+var Factory = make(map[string]func() Audit)
 
 const DefaultAuditor = "default"
 
+// Info describes an auditor. The information is shown by the -list option of
+// the audit command
+type Info struct {
+	Description string
+	Version     string
+	Category    string
+}
+
+// Describer is implemented by auditors that can describe themselves
+type Describer interface {
+	Info() Info
+}
+
+// Describe returns the Info for the auditor. The Info is empty if the auditor
+// does not implement the Describer interface
+func Describe(audit Audit) Info {
+	if d, ok := audit.(Describer); ok {
+		return d.Info()
+	}
+	return Info{}
+}
+
+// Register adds an auditor to the Factory. The function must return a new
+// instance of the auditor each time it is called. The value of any parameters
+// in the new instance is the default value for that parameter
+//
+// Register is intended to be called from the init() function of a package and
+// panics if an auditor with the same ID has already been registered. Auditors
+// defined in other packages are added to the audit command by importing the
+// package for its side effects
+func Register(f func() Audit) {
+	err := register(f)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// register adds an auditor to the Factory. returns an error if an auditor
+// with the same ID already exists
+func register(f func() Audit) error {
+	id := f().ID()
+	n := NormaliseID(id)
+	if n == "" {
+		return fmt.Errorf("auditor has no id")
+	}
+	if _, ok := Factory[n]; ok {
+		return fmt.Errorf("auditor already exists: %s", id)
+	}
+	Factory[n] = f
+	return nil
+}
+
+// the auditors defined in this package. the first auditor in the list is the
+// default auditor
 var definitions []func() Audit = []func() Audit{
-	func() Audit { return &coluxxCount{} },
-	func() Audit { return &highHue{} },
-	func() Audit { return &vsyncWithoutVblank{} },
-	func() Audit { return &shortVsync{} },
-	func() Audit { return &indeterminate{} },
-	func() Audit { return &mapper{} },
+	func() Audit { return &coluxxCount{frames: defaultFrames} },
+	func() Audit { return &highHue{frames: defaultFrames, minHue: 0x0e} },
+	func() Audit { return &vsyncWithoutVblank{frames: defaultFrames} },
+	func() Audit { return &shortVsync{frames: defaultFrames, min: 3} },
+	func() Audit { return &indeterminate{frames: defaultFrames} },
+	func() Audit { return &syncShape{maxFrames: defaultFrames} },
+	func() Audit { return &visibleArea{maxFrames: defaultFrames, tolerance: 2} },
+	func() Audit { return &collisions{frames: defaultFrames} },
+	func() Audit { return &arm{frames: defaultFrames} },
+	func() Audit { return &saveKey{frames: defaultFrames} },
 }
 
-// turn definitions into the Factory
+// register the definitions
 func init() {
-	Factory = make(map[string]func() Audit)
 	for _, f := range definitions {
-		aud := f()
-		Factory[NormaliseID(aud.ID())] = f
+		Register(f)
 	}
 	Factory[DefaultAuditor] = definitions[0]
 }
@@ -0,0 +1,306 @@
+package auditors_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jetsetilly/gopher2600-utils/audit/auditors"
+	"github.com/jetsetilly/gopher2600/cartridgeloader"
+	"github.com/jetsetilly/gopher2600/debugger/govern"
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/hardware/television"
+)
+
+// runAuditor runs the auditor with the ID on the ROM data in the same way as
+// the audit command. returns the severity of the result and the message
+func runAuditor(t *testing.T, id string, rom []byte) (auditors.Severity, string) {
+	t.Helper()
+
+	tv, err := television.NewTelevision("NTSC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tv.End()
+	tv.SetFPSCap(false)
+
+	vcs, err := hardware.NewVCS(auditors.Emulation, tv, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loader, err := cartridgeloader.NewLoaderFromData("synthetic.bin", rom, "AUTO", "AUTO", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = vcs.AttachCartridge(loader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = vcs.Reset()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	audit, err := auditors.New(auditors.NormaliseID(id), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = audit.Initialise(vcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d, ok := audit.(auditors.Detacher); ok {
+		defer d.Detach()
+	}
+
+	err = vcs.Run(func() (govern.State, error) {
+		if err := audit.Check(); err != nil {
+			return govern.Ending, err
+		}
+		return govern.Running, nil
+	})
+	if !errors.Is(err, auditors.CheckEnded) {
+		t.Fatalf("%s: emulation ended unexpectedly: %v", id, err)
+	}
+
+	var msg strings.Builder
+	err = audit.Finalise(&msg)
+	sev := auditors.SeverityOf(err)
+	if sev == auditors.Okay {
+		return sev, msg.String()
+	}
+	return sev, err.Error()
+}
+
+func TestAuditors(t *testing.T) {
+	tests := []struct {
+		name     string
+		auditor  string
+		opts     romOptions
+		severity auditors.Severity
+		msg      string
+
+		// part of the message that is expected when the full message depends
+		// on details of the emulation
+		contains string
+	}{
+		{
+			name:     "short vsync",
+			auditor:  "ShortVsync",
+			opts:     romOptions{vsyncLines: 1},
+			severity: auditors.Error,
+			msg:      "ROM generates a VSYNC signal that is too short",
+		},
+		{
+			name:     "normal vsync",
+			auditor:  "ShortVsync",
+			severity: auditors.Okay,
+		},
+		{
+			name:     "vsync without vblank",
+			auditor:  "VsyncWithoutVblank",
+			opts:     romOptions{vsyncWithoutVblank: true},
+			severity: auditors.Warning,
+			msg:      "ROM uses VSYNC without VBLANK",
+		},
+		{
+			name:     "vsync with vblank",
+			auditor:  "VsyncWithoutVblank",
+			severity: auditors.Okay,
+		},
+		{
+			name:     "hue $E",
+			auditor:  "HighHue",
+			opts:     romOptions{background: 0xe4},
+			severity: auditors.Warning,
+			msg:      "ROM uses colour-lum value of $Ex or $Fx",
+		},
+		{
+			name:     "hue $4",
+			auditor:  "HighHue",
+			opts:     romOptions{background: 0x44},
+			severity: auditors.Okay,
+		},
+		{
+			name:     "LAX immediate",
+			auditor:  "Indeterminate",
+			opts:     romOptions{lax: true},
+			severity: auditors.Error,
+			msg:      "ROM uses LAX (immediate)",
+		},
+		{
+			name:     "XAA",
+			auditor:  "Indeterminate",
+			opts:     romOptions{xaa: true},
+			severity: auditors.Error,
+			msg:      "ROM uses XAA",
+		},
+		{
+			name:     "LAX immediate and XAA",
+			auditor:  "Indeterminate",
+			opts:     romOptions{lax: true, xaa: true},
+			severity: auditors.Error,
+			msg:      "ROM uses both LAX (immediate) and XAA",
+		},
+		{
+			name:     "no indeterminate instructions",
+			auditor:  "Indeterminate",
+			severity: auditors.Okay,
+		},
+		{
+			name:     "sync shape",
+			auditor:  "SyncShape",
+			severity: auditors.Okay,
+		},
+		{
+			name:     "sync shape mid-line vsync",
+			auditor:  "SyncShape",
+			opts:     romOptions{midlineVsync: true},
+			severity: auditors.Warning,
+			contains: "VSYNC starts mid-line in",
+		},
+		{
+			name:     "sync shape without vblank",
+			auditor:  "SyncShape",
+			opts:     romOptions{vsyncWithoutVblank: true},
+			severity: auditors.Warning,
+			contains: "VBLANK does not cover VSYNC in",
+		},
+		{
+			name:     "drawn above safe area",
+			auditor:  "VisibleArea",
+			opts:     romOptions{background: 0x44, drawVblank: true},
+			severity: auditors.Warning,
+			contains: "draws above safe area",
+		},
+		{
+			name:     "no collision registers",
+			auditor:  "Collisions",
+			severity: auditors.Okay,
+			msg:      "no collisions latched | no collision registers read",
+		},
+		{
+			name:     "collisions latched but not read",
+			auditor:  "Collisions",
+			opts:     romOptions{drawObjects: true},
+			severity: auditors.Okay,
+			contains: "frames: CXP0FB ",
+		},
+		{
+			name:     "collisions without CXCLR",
+			auditor:  "Collisions",
+			opts:     romOptions{readCollisions: true},
+			severity: auditors.Warning,
+			contains: "collision registers read but CXCLR never written",
+		},
+		{
+			name:     "collisions read after CXCLR",
+			auditor:  "Collisions",
+			opts:     romOptions{readCollisions: true, cxclrVblank: true},
+			severity: auditors.Okay,
+			contains: "read after CXCLR before drawing: CXP0FB",
+		},
+		{
+			name:     "collisions read before objects drawn",
+			auditor:  "Collisions",
+			opts:     romOptions{readCollisions: true, cxclrOverscan: true, drawObjects: true},
+			severity: auditors.Okay,
+			contains: "read after CXCLR before drawing: CXP0FB",
+		},
+		{
+			name:     "collisions read after objects drawn",
+			auditor:  "Collisions",
+			opts:     romOptions{readOverscan: true, cxclrOverscan: true, drawObjects: true},
+			severity: auditors.Okay,
+			contains: "read: CXP0FB",
+		},
+		{
+			name:     "no savekey access",
+			auditor:  "SaveKey",
+			severity: auditors.Okay,
+			msg:      "no EEPROM access",
+		},
+		{
+			name:     "savekey write",
+			auditor:  "SaveKey",
+			opts:     romOptions{eeprom: true, eepromAdr: 0x3000},
+			severity: auditors.Okay,
+			msg:      "writes pages 192",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sev, msg := runAuditor(t, tt.auditor, syntheticROM(tt.opts))
+			if sev != tt.severity {
+				t.Errorf("expected severity %s, got %s (%s)", tt.severity, sev, msg)
+			}
+			if tt.msg != "" && msg != tt.msg {
+				t.Errorf("expected message %q, got %q", tt.msg, msg)
+			}
+			if tt.contains != "" && !strings.Contains(msg, tt.contains) {
+				t.Errorf("expected message containing %q, got %q", tt.contains, msg)
+			}
+		})
+	}
+}
+
+// every auditor should run to completion on a well behaved ROM without
+// reporting an error
+func TestFactory(t *testing.T) {
+	rom := syntheticROM(romOptions{background: 0x44})
+
+	for key, f := range auditors.Factory {
+		if key == auditors.DefaultAuditor {
+			continue
+		}
+		id := f().ID()
+		t.Run(id, func(t *testing.T) {
+			sev, msg := runAuditor(t, id, rom)
+			if sev == auditors.Error {
+				t.Errorf("unexpected error: %s", msg)
+			}
+		})
+	}
+}
+
+// parameters outside of the range accepted by the auditor should be rejected
+func TestParamRange(t *testing.T) {
+	for _, params := range []string{"min=15", "min=$0f"} {
+		_, err := auditors.New(auditors.NormaliseID("HighHue"), params)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", params, err)
+		}
+	}
+	for _, params := range []string{"min=16", "min=$ff", "frames=0", "frames=-5"} {
+		_, err := auditors.New(auditors.NormaliseID("HighHue"), params)
+		if err == nil {
+			t.Errorf("%s: expected error", params)
+		}
+	}
+}
+
+// registering an auditor with the same ID as an existing auditor should panic
+func TestRegisterDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected panic when registering a duplicate auditor")
+		}
+	}()
+
+	// IDs are not case sensitive
+	auditors.Register(func() auditors.Audit {
+		return &duplicate{id: "shortvsync"}
+	})
+}
+
+// duplicate is an auditor with an ID chosen by the test
+type duplicate struct {
+	id string
+}
+
+func (audit *duplicate) ID() string                          { return audit.id }
+func (audit *duplicate) Initialise(vcs *hardware.VCS) error  { return nil }
+func (audit *duplicate) Check() error                        { return auditors.CheckEnded }
+func (audit *duplicate) Finalise(msg *strings.Builder) error { return auditors.FinalisedOk }
@@ -0,0 +1,235 @@
+package auditors
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cpubus"
+	"github.com/jetsetilly/gopher2600/hardware/television/frameinfo"
+	"github.com/jetsetilly/gopher2600/hardware/television/specification"
+)
+
+// the collision registers in the order they are reported
+var collisionRegisters = []cpubus.Register{
+	cpubus.CXM0P,
+	cpubus.CXM1P,
+	cpubus.CXP0FB,
+	cpubus.CXP1FB,
+	cpubus.CXM0FB,
+	cpubus.CXM1FB,
+	cpubus.CXBLPF,
+	cpubus.CXPPMM,
+}
+
+// collisions notes the collisions seen by the TIA collision latches and how
+// the ROM uses the collision registers
+type collisions struct {
+	vcs     *hardware.VCS
+	frameCt int
+
+	// map of read address to collision register
+	registers map[uint16]cpubus.Register
+
+	// the collision latches have been set since the start of the frame
+	latched map[cpubus.Register]bool
+
+	// number of frames in which each collision latch was set and the number
+	// of frames in which any latch was set
+	latchFrames         map[cpubus.Register]int
+	framesWithCollision int
+
+	// number of reads of each register and how many of those reads had one or
+	// both collision bits set
+	reads    map[cpubus.Register]int
+	observed map[cpubus.Register]int
+
+	// CXCLR has been written to at least once
+	cleared bool
+
+	// the beam has passed over the visible part of the screen since CXCLR was
+	// most recently written
+	drawn bool
+
+	// the scanline of the beam at the previous instruction
+	beamScanline int
+
+	// reads of collision registers made after CXCLR but before the beam has
+	// drawn anything
+	premature map[cpubus.Register]int
+
+	// parameters
+	frames int
+}
+
+// ID implements the Audit interface
+func (audit *collisions) ID() string {
+	return "Collisions"
+}
+
+// Info implements the Describer interface
+func (audit *collisions) Info() Info {
+	return Info{
+		Description: "Collisions seen by the TIA collision latches and use of the collision registers and CXCLR",
+		Version:     "1.0",
+		Category:    "video",
+	}
+}
+
+// Params implements the Configurable interface
+func (audit *collisions) Params() []Param {
+	return []Param{
+		{Name: framesParam, Help: "number of frames to run the ROM for", Value: &audit.frames},
+	}
+}
+
+// Initialise implements the Audit interface
+func (audit *collisions) Initialise(vcs *hardware.VCS) error {
+	audit.vcs = vcs
+	audit.vcs.TV.AddFrameTrigger(audit)
+
+	audit.registers = make(map[uint16]cpubus.Register)
+	for _, r := range collisionRegisters {
+		audit.registers[cpubus.ReadAddressByRegister[r]] = r
+	}
+	audit.latched = make(map[cpubus.Register]bool)
+	audit.latchFrames = make(map[cpubus.Register]int)
+	audit.reads = make(map[cpubus.Register]int)
+	audit.observed = make(map[cpubus.Register]int)
+	audit.premature = make(map[cpubus.Register]int)
+
+	return nil
+}
+
+// peek notes which collision latches are set. the latches stay set until CXCLR
+// is written so peeking after every instruction means that the latches are
+// seen before they are cleared
+func (audit *collisions) peek() error {
+	for _, r := range collisionRegisters {
+		v, err := audit.vcs.Mem.Peek(cpubus.ReadAddressByRegister[r])
+		if err != nil {
+			return err
+		}
+
+		// collision bits are in bits 6 and 7 of the register
+		if v&0xc0 != 0 {
+			audit.latched[r] = true
+		}
+	}
+	return nil
+}
+
+// Check implements the Audit interface
+func (audit *collisions) Check() error {
+	if audit.frameCt > audit.frames {
+		return CheckEnded
+	}
+
+	// the beam has drawn something if it is in the visible part of the screen
+	// or if it has passed over the visible part of a scanline since the
+	// previous instruction. the CPU is often halted by WSYNC so the beam can
+	// pass over many pixels between instructions
+	sig := audit.vcs.TV.GetLastSignal()
+	scanline := sig.Index / specification.ClksScanline
+	pixel := sig.Index%specification.ClksScanline - specification.ClksHBlank
+	if !sig.VBlank && (pixel >= 0 || scanline != audit.beamScanline) {
+		audit.drawn = true
+	}
+	audit.beamScanline = scanline
+
+	err := audit.peek()
+	if err != nil {
+		return err
+	}
+
+	addr := audit.vcs.Mem.LastCPUAddressMapped
+
+	if audit.vcs.Mem.LastCPUWrite {
+		if addr == cpubus.WriteAddressByRegister[cpubus.CXCLR] {
+			audit.cleared = true
+			audit.drawn = false
+		}
+		return nil
+	}
+
+	r, ok := audit.registers[addr]
+	if !ok {
+		return nil
+	}
+
+	audit.reads[r]++
+	if audit.cleared && !audit.drawn {
+		audit.premature[r]++
+	}
+
+	if audit.vcs.Mem.LastCPUData&0xc0 != 0 {
+		audit.observed[r]++
+	}
+
+	return nil
+}
+
+// Finalise implements the Audit interface
+func (audit *collisions) Finalise(msg *strings.Builder) error {
+	var latched []string
+	var read []string
+	var premature []string
+	for _, r := range collisionRegisters {
+		if audit.latchFrames[r] > 0 {
+			latched = append(latched, fmt.Sprintf("%s %d", r, audit.latchFrames[r]))
+		}
+		if audit.reads[r] > 0 {
+			read = append(read, fmt.Sprintf("%s %d/%d", r, audit.observed[r], audit.reads[r]))
+		}
+		if audit.premature[r] > 0 {
+			premature = append(premature, fmt.Sprintf("%s %d", r, audit.premature[r]))
+		}
+	}
+
+	var s []string
+	if len(latched) == 0 {
+		s = append(s, "no collisions latched")
+	} else {
+		s = append(s, fmt.Sprintf("latched in %d frames: %s", audit.framesWithCollision, strings.Join(latched, ", ")))
+	}
+	if len(read) == 0 {
+		s = append(s, "no collision registers read")
+	} else {
+		s = append(s, fmt.Sprintf("read: %s", strings.Join(read, ", ")))
+	}
+
+	// reads before anything is drawn are not necessarily a fault. the ROM
+	// may not be interested in the result of the read
+	if len(premature) > 0 {
+		s = append(s, fmt.Sprintf("read after CXCLR before drawing: %s", strings.Join(premature, ", ")))
+	}
+
+	summary := strings.Join(s, " | ")
+
+	if len(read) > 0 && !audit.cleared {
+		return Warningf("collision registers read but CXCLR never written | %s", summary)
+	}
+
+	msg.WriteString(summary)
+	return FinalisedOk
+}
+
+// NewFrame implements the television.FrameTrigger() interface
+func (audit *collisions) NewFrame(frameInfo frameinfo.Current) error {
+	audit.frameCt++
+
+	err := audit.peek()
+	if err != nil {
+		return err
+	}
+
+	for r := range audit.latched {
+		audit.latchFrames[r]++
+	}
+	if len(audit.latched) > 0 {
+		audit.framesWithCollision++
+	}
+	clear(audit.latched)
+
+	return nil
+}
@@ -13,6 +13,9 @@ type coluxxCount struct {
 	vcs     *hardware.VCS
 	frameCt int
 
+	// parameters
+	frames int
+
 	colourCounts [2][128]int
 }
 
@@ -21,6 +24,22 @@ func (audit *coluxxCount) ID() string {
 	return "COLUxxCount"
 }
 
+// Info implements the Describer interface
+func (audit *coluxxCount) Info() Info {
+	return Info{
+		Description: "Count the number of colour values written to the COLUxx registers",
+		Version:     "1.0",
+		Category:    "colour",
+	}
+}
+
+// Params implements the Configurable interface
+func (audit *coluxxCount) Params() []Param {
+	return []Param{
+		{Name: framesParam, Help: "number of frames to run the ROM for", Value: &audit.frames},
+	}
+}
+
 // Initialise implements the Audit interface
 func (audit *coluxxCount) Initialise(vcs *hardware.VCS) error {
 	audit.vcs = vcs
@@ -30,7 +49,7 @@ func (audit *coluxxCount) Initialise(vcs *hardware.VCS) error {
 
 // Check implements the Audit interface
 func (audit *coluxxCount) Check() error {
-	if audit.frameCt > 60 {
+	if audit.frameCt > audit.frames {
 		return CheckEnded
 	}
 
@@ -5,14 +5,27 @@ import (
 	"strings"
 
 	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cpubus"
 	"github.com/jetsetilly/gopher2600/hardware/television/frameinfo"
 	"github.com/jetsetilly/gopher2600/hardware/television/signal"
 )
 
 type highHue struct {
+	snapshotRequest
+
 	vcs         *hardware.VCS
 	frameCt     int
 	usesHighHue bool
+
+	// map of write address to the COLUxx registers
+	colu map[uint16]cpubus.Register
+
+	// a snapshot has been requested for a write of a high hue
+	snapshotTaken bool
+
+	// parameters
+	frames int
+	minHue uint8
 }
 
 // ID implements the Audit interface
@@ -20,25 +33,76 @@ func (audit *highHue) ID() string {
 	return "HighHue"
 }
 
+// Info implements the Describer interface
+func (audit *highHue) Info() Info {
+	return Info{
+		Description: "Screens drawn with high hue values",
+		Version:     "1.0",
+		Category:    "colour",
+	}
+}
+
+// Params implements the Configurable interface
+func (audit *highHue) Params() []Param {
+	return []Param{
+		{Name: framesParam, Help: "number of frames to run the ROM for", Value: &audit.frames},
+		{Name: "min", Help: "lowest hue (0 to 15) that is reported", Value: &audit.minHue},
+	}
+}
+
+// validate implements the validator interface
+func (audit *highHue) validate() error {
+	if audit.minHue > 0x0f {
+		return fmt.Errorf("min: hue must be between 0 and 15: %d", audit.minHue)
+	}
+	return nil
+}
+
 // Initialise implements the Audit interface
 func (audit *highHue) Initialise(vcs *hardware.VCS) error {
 	audit.vcs = vcs
 	audit.vcs.TV.AddPixelRenderer(audit)
+
+	audit.colu = make(map[uint16]cpubus.Register)
+	for _, r := range []cpubus.Register{cpubus.COLUP0, cpubus.COLUP1, cpubus.COLUPF, cpubus.COLUBK} {
+		audit.colu[cpubus.WriteAddressByRegister[r]] = r
+	}
+
 	return nil
 }
 
 // Check implements the Audit interface
 func (audit *highHue) Check() error {
-	if audit.frameCt > 60 {
+	if audit.frameCt > audit.frames {
 		return CheckEnded
 	}
+
+	// the finding is decided by the pixels drawn to the screen but the
+	// snapshot is taken at the moment the high hue is written to the TIA
+	if !audit.snapshotTaken && audit.vcs.Mem.LastCPUWrite && audit.vcs.TV.GetFrameInfo().Stable {
+		if r, ok := audit.colu[audit.vcs.Mem.LastCPUAddressMapped]; ok {
+			v := audit.vcs.Mem.LastCPUData
+			if v != 0x00 && v>>4 >= audit.minHue {
+				audit.snapshotTaken = true
+				audit.snapshot(fmt.Sprintf("colour-lum value $%02x written to %s", v, r))
+			}
+		}
+	}
+
 	return nil
 }
 
 // Finalise implements the Audit interface
 func (audit *highHue) Finalise(_ *strings.Builder) error {
 	if audit.usesHighHue {
-		return fmt.Errorf("ROM uses colour-lum value of $Ex or $Fx")
+		var hues []string
+		for h := audit.minHue; h <= 0x0f; h++ {
+			hues = append(hues, fmt.Sprintf("$%Xx", h))
+		}
+		if len(hues) > 1 {
+			hues = append(hues[:len(hues)-2], fmt.Sprintf("%s or %s", hues[len(hues)-2], hues[len(hues)-1]))
+		}
+		return Warningf("ROM uses colour-lum value of %s", strings.Join(hues, ", "))
 	}
 	return FinalisedOk
 }
@@ -63,7 +127,7 @@ func (audit *highHue) SetPixels(sig []signal.SignalAttributes, last int) error {
 	for i := 0; i <= last; i++ {
 		if !sig[i].VBlank && sig[i].Color != 0x00 {
 			hue := (uint8(sig[i].Color) & 0xf0) >> 4
-			if hue == 0x0e || hue == 0x0f {
+			if hue >= audit.minHue {
 				audit.usesHighHue = true
 				return nil
 			}
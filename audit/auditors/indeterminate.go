@@ -9,10 +9,15 @@ import (
 )
 
 type indeterminate struct {
+	snapshotRequest
+
 	vcs     *hardware.VCS
 	frameCt int
 	hasLAX  bool
 	hasXAA  bool
+
+	// parameters
+	frames int
 }
 
 // ID implements the Audit interface
@@ -20,6 +25,22 @@ func (audit *indeterminate) ID() string {
 	return "Indeterminate"
 }
 
+// Info implements the Describer interface
+func (audit *indeterminate) Info() Info {
+	return Info{
+		Description: "Use of the LAX (immediate) and XAA instructions, which are unstable on real hardware",
+		Version:     "1.0",
+		Category:    "cpu",
+	}
+}
+
+// Params implements the Configurable interface
+func (audit *indeterminate) Params() []Param {
+	return []Param{
+		{Name: framesParam, Help: "number of frames to run the ROM for", Value: &audit.frames},
+	}
+}
+
 // Initialise implements the Audit interface
 func (audit *indeterminate) Initialise(vcs *hardware.VCS) error {
 	audit.vcs = vcs
@@ -29,12 +50,18 @@ func (audit *indeterminate) Initialise(vcs *hardware.VCS) error {
 
 // Check implements the Audit interface
 func (audit *indeterminate) Check() error {
-	if audit.frameCt > 60 {
+	if audit.frameCt > audit.frames {
 		return CheckEnded
 	}
 	if audit.vcs.CPU.LastResult.Final {
-		audit.hasLAX = audit.hasLAX || audit.vcs.CPU.LastResult.Defn.OpCode == 0xab
-		audit.hasXAA = audit.hasXAA || audit.vcs.CPU.LastResult.Defn.Operator == 0x8b
+		if audit.vcs.CPU.LastResult.Defn.OpCode == 0xab && !audit.hasLAX {
+			audit.hasLAX = true
+			audit.snapshot("LAX (immediate)")
+		}
+		if audit.vcs.CPU.LastResult.Defn.OpCode == 0x8b && !audit.hasXAA {
+			audit.hasXAA = true
+			audit.snapshot("XAA")
+		}
 	}
 	return nil
 }
@@ -0,0 +1,164 @@
+package auditors
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// the number of frames the built-in auditors run for unless the frames
+// parameter is specified
+const defaultFrames = 60
+
+// the name of the parameter shared by auditors that run for a number of frames
+const framesParam = "frames"
+
+// Param is a single parameter of an auditor. The Value field is a pointer to
+// the field in the auditor that is changed by the parameter. The value of the
+// field when the auditor is created is the default value of the parameter
+//
+// Supported pointer types are *int, *bool, *uint8 and *string. Values for
+// *uint8 parameters can be specified in decimal or in hexadecimal with a $ or
+// 0x prefix
+type Param struct {
+	Name  string
+	Help  string
+	Value any
+}
+
+// Configurable is implemented by auditors that accept parameters
+type Configurable interface {
+	Params() []Param
+}
+
+// validator is implemented by auditors that have parameters with a range of
+// values narrower than the type of the parameter
+type validator interface {
+	validate() error
+}
+
+// Type returns the name of the type of the parameter
+func (p Param) Type() string {
+	switch p.Value.(type) {
+	case *int:
+		return "int"
+	case *bool:
+		return "bool"
+	case *uint8:
+		return "byte"
+	case *string:
+		return "string"
+	}
+	return "unknown"
+}
+
+// Default returns the current value of the parameter as a string. Called on a
+// new instance of an auditor this will be the default value
+func (p Param) Default() string {
+	switch v := p.Value.(type) {
+	case *int:
+		return strconv.Itoa(*v)
+	case *bool:
+		return strconv.FormatBool(*v)
+	case *uint8:
+		return fmt.Sprintf("$%02x", *v)
+	case *string:
+		return *v
+	}
+	return ""
+}
+
+// set the parameter from the string representation of the value
+func (p Param) set(s string) error {
+	switch v := p.Value.(type) {
+	case *int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("not an int value: %s", s)
+		}
+		*v = n
+	case *bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("not a bool value: %s", s)
+		}
+		*v = b
+	case *uint8:
+		b, err := parseRuleByte(s)
+		if err != nil {
+			return err
+		}
+		*v = b
+	case *string:
+		*v = s
+	default:
+		return fmt.Errorf("unsupported parameter type")
+	}
+	return nil
+}
+
+// Configure sets the parameters of the auditor. The params string is a comma
+// separated list of name=value pairs. For example, "min=3,frames=300"
+func Configure(audit Audit, params string) error {
+	params = strings.TrimSpace(params)
+	if params == "" {
+		return nil
+	}
+
+	cfg, ok := audit.(Configurable)
+	if !ok {
+		return fmt.Errorf("%s: auditor does not accept parameters", audit.ID())
+	}
+
+	for _, kv := range strings.Split(params, ",") {
+		name, value, ok := strings.Cut(kv, "=")
+		if !ok {
+			return fmt.Errorf("%s: parameter must be in the form name=value: %s", audit.ID(), kv)
+		}
+		name = strings.TrimSpace(name)
+
+		var found bool
+		for _, p := range cfg.Params() {
+			if strings.EqualFold(p.Name, name) {
+				err := p.set(strings.TrimSpace(value))
+				if err != nil {
+					return fmt.Errorf("%s: %s: %w", audit.ID(), p.Name, err)
+				}
+				if n, ok := p.Value.(*int); ok && p.Name == framesParam && *n < 1 {
+					return fmt.Errorf("%s: %s: must be at least 1: %d", audit.ID(), p.Name, *n)
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: unknown parameter: %s", audit.ID(), name)
+		}
+	}
+
+	if v, ok := audit.(validator); ok {
+		err := v.validate()
+		if err != nil {
+			return fmt.Errorf("%s: %w", audit.ID(), err)
+		}
+	}
+
+	return nil
+}
+
+// New creates an instance of the auditor with the normalised ID and sets the
+// parameters as described by Configure()
+func New(id string, params string) (Audit, error) {
+	f, ok := Factory[id]
+	if !ok {
+		return nil, fmt.Errorf("invalid auditor: %s", id)
+	}
+
+	audit := f()
+	err := Configure(audit, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return audit, nil
+}
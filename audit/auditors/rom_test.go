@@ -0,0 +1,266 @@
+package auditors_test
+
+// TIA write registers used by the synthetic ROMs
+const (
+	tiaVSYNC  = 0x00
+	tiaVBLANK = 0x01
+	tiaWSYNC  = 0x02
+	tiaCOLUBK = 0x09
+	tiaPF0    = 0x0d
+	tiaPF1    = 0x0e
+	tiaPF2    = 0x0f
+	tiaRESP0  = 0x10
+	tiaGRP0   = 0x1b
+	tiaCXCLR  = 0x2c
+)
+
+// TIA read registers used by the synthetic ROMs
+const (
+	tiaCXP0FB = 0x02
+)
+
+// RIOT registers used by the synthetic ROMs
+const (
+	riotSWCHA  = 0x0280
+	riotSWACNT = 0x0281
+)
+
+// the origin of a 2K ROM in the 6507 address space
+const romOrigin = 0xf800
+
+// romBuilder assembles a tiny 2K ROM. only the handful of instructions needed
+// by the tests are supported
+type romBuilder struct {
+	code []byte
+}
+
+// pc returns the address of the next instruction
+func (b *romBuilder) pc() uint16 {
+	return romOrigin + uint16(len(b.code))
+}
+
+func (b *romBuilder) emit(bytes ...byte) {
+	b.code = append(b.code, bytes...)
+}
+
+func (b *romBuilder) lda(v uint8)  { b.emit(0xa9, v) }
+func (b *romBuilder) ldx(v uint8)  { b.emit(0xa2, v) }
+func (b *romBuilder) lax(v uint8)  { b.emit(0xab, v) }
+func (b *romBuilder) xaa(v uint8)  { b.emit(0x8b, v) }
+func (b *romBuilder) sta(zp uint8) { b.emit(0x85, zp) }
+func (b *romBuilder) ldz(zp uint8) { b.emit(0xa5, zp) }
+func (b *romBuilder) nop()         { b.emit(0xea) }
+
+// staAbs stores the A register to an absolute address
+func (b *romBuilder) staAbs(addr uint16) {
+	b.emit(0x8d, uint8(addr), uint8(addr>>8))
+}
+
+// i2c sets the state of the I2C lines of a SaveKey in the right port
+func (b *romBuilder) i2c(sda bool, scl bool) {
+	var v uint8
+	if sda {
+		v |= 0x04
+	}
+	if scl {
+		v |= 0x08
+	}
+	b.lda(v)
+	b.staAbs(riotSWCHA)
+}
+
+// i2cByte clocks out a byte on the I2C lines followed by the acknowledge
+// clock, during which the ROM releases SDA
+func (b *romBuilder) i2cByte(v uint8) {
+	for i := 7; i >= 0; i-- {
+		sda := v&(1<<i) != 0
+		b.i2c(sda, false)
+		b.i2c(sda, true)
+		b.i2c(sda, false)
+	}
+	b.i2c(true, false)
+	b.i2c(true, true)
+	b.i2c(true, false)
+}
+
+// eepromWrite writes a single byte to the EEPROM of a SaveKey
+func (b *romBuilder) eepromWrite(addr uint16, v uint8) {
+	b.lda(0x0c)
+	b.staAbs(riotSWACNT)
+
+	// start condition
+	b.i2c(true, true)
+	b.i2c(false, true)
+	b.i2c(false, false)
+
+	b.i2cByte(0xa0)
+	b.i2cByte(uint8(addr >> 8))
+	b.i2cByte(uint8(addr))
+	b.i2cByte(v)
+
+	// stop condition
+	b.i2c(false, false)
+	b.i2c(false, true)
+	b.i2c(true, true)
+}
+
+// wsync waits for the start of the next n scanlines. uses the X register
+func (b *romBuilder) wsync(n uint8) {
+	b.ldx(n)
+	loop := b.pc()
+	b.sta(tiaWSYNC)
+	b.emit(0xca)
+	b.emit(0xd0, uint8(int(loop)-int(b.pc())-2))
+}
+
+// jmp to an absolute address
+func (b *romBuilder) jmp(addr uint16) {
+	b.emit(0x4c, uint8(addr), uint8(addr>>8))
+}
+
+// bytes returns the 2K ROM with the reset vector pointing to the first
+// instruction
+func (b *romBuilder) bytes() []byte {
+	rom := make([]byte, 2048)
+	copy(rom, b.code)
+	rom[0x7fc] = uint8(romOrigin & 0xff)
+	rom[0x7fd] = uint8(romOrigin >> 8)
+	return rom
+}
+
+// the options for a synthetic ROM. the zero value creates a well behaved NTSC
+// ROM
+type romOptions struct {
+	// number of VSYNC scanlines. three if zero
+	vsyncLines uint8
+
+	// turn VBLANK off during VSYNC
+	vsyncWithoutVblank bool
+
+	// colour of the background in the visible area
+	background uint8
+
+	// include a LAX immediate or an XAA instruction in the frame loop
+	lax bool
+	xaa bool
+
+	// start VSYNC after the end of HBLANK
+	midlineVsync bool
+
+	// end VBLANK immediately after VSYNC and set the background colour
+	// before the vertical blank, so that the frame is drawn from the top
+	drawVblank bool
+
+	// draw player 0 over a playfield that covers the whole of the visible area
+	drawObjects bool
+
+	// read CXP0FB at the start of the vertical blank or at the start of the
+	// overscan (before CXCLR is written)
+	readCollisions bool
+	readOverscan   bool
+
+	// write to CXCLR at the start of the vertical blank (before the collision
+	// register is read) or at the start of the overscan
+	cxclrVblank   bool
+	cxclrOverscan bool
+
+	// write a byte to the EEPROM of a SaveKey before the first frame
+	eeprom    bool
+	eepromAdr uint16
+}
+
+// syntheticROM creates a ROM that generates a 262 scanline frame in the way
+// described by the options
+func syntheticROM(opts romOptions) []byte {
+	var b romBuilder
+
+	if opts.vsyncLines == 0 {
+		opts.vsyncLines = 3
+	}
+
+	// sei, cld, ldx #$ff, txs
+	b.emit(0x78, 0xd8)
+	b.ldx(0xff)
+	b.emit(0x9a)
+
+	if opts.eeprom {
+		b.eepromWrite(opts.eepromAdr, 0x55)
+	}
+
+	frame := b.pc()
+
+	// vsync
+	if opts.vsyncWithoutVblank {
+		b.lda(0x00)
+		b.sta(tiaVBLANK)
+	} else {
+		b.lda(0x02)
+		b.sta(tiaVBLANK)
+	}
+	if opts.midlineVsync {
+		// each NOP takes six colour clocks
+		for range 20 {
+			b.nop()
+		}
+	}
+	b.lda(0x02)
+	b.sta(tiaVSYNC)
+	b.wsync(opts.vsyncLines)
+	b.lda(0x00)
+	b.sta(tiaVSYNC)
+
+	// vblank. the total number of scanlines is the same whatever the length
+	// of the VSYNC signal
+	if opts.drawVblank {
+		b.lda(opts.background)
+		b.sta(tiaCOLUBK)
+		b.lda(0x00)
+	} else {
+		b.lda(0x02)
+	}
+	b.sta(tiaVBLANK)
+	if opts.cxclrVblank {
+		b.sta(tiaCXCLR)
+	}
+	if opts.readCollisions {
+		b.ldz(tiaCXP0FB)
+	}
+	if opts.drawObjects {
+		b.lda(0xff)
+		b.sta(tiaGRP0)
+		b.sta(tiaPF0)
+		b.sta(tiaPF1)
+		b.sta(tiaPF2)
+		b.sta(tiaRESP0)
+	}
+	b.wsync(40 - opts.vsyncLines)
+	b.lda(0x00)
+	b.sta(tiaVBLANK)
+
+	if opts.lax {
+		b.lax(0x00)
+	}
+	if opts.xaa {
+		b.xaa(0x00)
+	}
+
+	// visible area
+	b.lda(opts.background)
+	b.sta(tiaCOLUBK)
+	b.wsync(192)
+
+	// overscan
+	b.lda(0x02)
+	b.sta(tiaVBLANK)
+	if opts.readOverscan {
+		b.ldz(tiaCXP0FB)
+	}
+	if opts.cxclrOverscan {
+		b.sta(tiaCXCLR)
+	}
+	b.wsync(30)
+
+	b.jmp(frame)
+
+	return b.bytes()
+}
@@ -0,0 +1,249 @@
+package auditors
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cpubus"
+	"github.com/jetsetilly/gopher2600/hardware/television/frameinfo"
+)
+
+// Rule is a declarative auditor definition. A rule describes a write to a
+// register, with a value matching the mask/value pair, that happens during the
+// frame range From to To (inclusive). If the write is seen then the Finding is
+// reported by the auditor
+//
+// Rules are loaded from JSON files with LoadRules(). An example rule file:
+//
+//	[
+//		{
+//			"id": "HighBackground",
+//			"register": "COLUBK",
+//			"mask": "$f0",
+//			"value": "$e0",
+//			"from": 0,
+//			"to": 60,
+//			"finding": "ROM writes hue $E to COLUBK",
+//			"severity": "warning"
+//		}
+//	]
+//
+// Mask and value can be specified in decimal or in hexadecimal with either a $
+// or 0x prefix. An empty mask is the same as $ff and an empty value means
+// that any write to the register will match. A mask cannot be given without a
+// value. If To is omitted the rule runs for the same number of frames as the
+// built-in auditors. The severity of the finding is "error" unless otherwise
+// specified
+//
+// The ID is used in the names of snapshot directories and so cannot contain a
+// path separator or be a relative directory name
+type Rule struct {
+	ID       string `json:"id"`
+	Register string `json:"register"`
+	Mask     string `json:"mask"`
+	Value    string `json:"value"`
+	From     int    `json:"from"`
+	To       *int   `json:"to"`
+	Finding  string `json:"finding"`
+	Severity string `json:"severity"`
+}
+
+// the number of frames to run a rule for if the To field is omitted. the same
+// number of frames as the built-in auditors
+const defaultRuleFrames = defaultFrames
+
+// parseRuleByte parses a string representation of a byte value as used in the
+// mask and value fields of a Rule
+func parseRuleByte(s string) (uint8, error) {
+	s = strings.TrimSpace(s)
+	if v, ok := strings.CutPrefix(s, "$"); ok {
+		s = "0x" + v
+	}
+	v, err := strconv.ParseUint(s, 0, 8)
+	if err != nil {
+		return 0, fmt.Errorf("not a byte value: %s", s)
+	}
+	return uint8(v), nil
+}
+
+// LoadRules reads the rules in the named JSON file and adds them to the
+// Factory. Rules are checked for validity and for an ID that clashes with an
+// existing auditor
+func LoadRules(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("rules: %w", err)
+	}
+
+	var rules []Rule
+	err = json.Unmarshal(data, &rules)
+	if err != nil {
+		return fmt.Errorf("rules: %s: %w", filename, err)
+	}
+
+	for _, r := range rules {
+		f, err := r.factory()
+		if err != nil {
+			return fmt.Errorf("rules: %s: %w", filename, err)
+		}
+
+		err = register(f)
+		if err != nil {
+			return fmt.Errorf("rules: %s: %w", filename, err)
+		}
+	}
+
+	return nil
+}
+
+// factory checks the rule for validity and returns a function suitable for
+// adding to the Factory
+func (r Rule) factory() (func() Audit, error) {
+	if r.ID == "" {
+		return nil, fmt.Errorf("rule has no id")
+	}
+	if strings.ContainsAny(r.ID, `/\`) || r.ID == "." || r.ID == ".." {
+		return nil, fmt.Errorf("%s: rule id is not a valid directory name", r.ID)
+	}
+	if r.Finding == "" {
+		return nil, fmt.Errorf("%s: rule has no finding", r.ID)
+	}
+
+	addr, ok := cpubus.WriteAddressByRegister[cpubus.Register(strings.ToUpper(r.Register))]
+	if !ok {
+		return nil, fmt.Errorf("%s: unrecognised write register: %s", r.ID, r.Register)
+	}
+
+	if r.Mask != "" && r.Value == "" {
+		return nil, fmt.Errorf("%s: mask given without a value", r.ID)
+	}
+
+	var mask, value uint8
+	if r.Value != "" {
+		var err error
+
+		mask = 0xff
+		if r.Mask != "" {
+			mask, err = parseRuleByte(r.Mask)
+			if err != nil {
+				return nil, fmt.Errorf("%s: mask: %w", r.ID, err)
+			}
+		}
+
+		value, err = parseRuleByte(r.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: value: %w", r.ID, err)
+		}
+	}
+
+	severity := Error
+	if r.Severity != "" {
+		var err error
+		severity, err = ParseSeverity(r.Severity)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", r.ID, err)
+		}
+		if severity == Okay {
+			return nil, fmt.Errorf("%s: finding cannot have a severity of okay", r.ID)
+		}
+	}
+
+	to := defaultRuleFrames
+	if r.To != nil {
+		to = *r.To
+	}
+	if r.From < 0 || r.From > to {
+		return nil, fmt.Errorf("%s: invalid frame range: %d to %d", r.ID, r.From, to)
+	}
+
+	return func() Audit {
+		return &ruleAudit{
+			rule:     r,
+			addr:     addr,
+			mask:     mask,
+			value:    value & mask,
+			to:       to,
+			severity: severity,
+		}
+	}, nil
+}
+
+type ruleAudit struct {
+	snapshotRequest
+
+	vcs     *hardware.VCS
+	frameCt int
+
+	rule     Rule
+	addr     uint16
+	mask     uint8
+	value    uint8
+	to       int
+	severity Severity
+
+	// whether the rule has been matched and on which frame it was first matched
+	matched    bool
+	matchFrame int
+}
+
+// ID implements the Audit interface
+func (audit *ruleAudit) ID() string {
+	return audit.rule.ID
+}
+
+// Info implements the Describer interface
+func (audit *ruleAudit) Info() Info {
+	return Info{
+		Description: audit.rule.Finding,
+		Category:    "rule",
+	}
+}
+
+// Initialise implements the Audit interface
+func (audit *ruleAudit) Initialise(vcs *hardware.VCS) error {
+	audit.vcs = vcs
+	audit.vcs.TV.AddFrameTrigger(audit)
+	return nil
+}
+
+// Check implements the Audit interface
+func (audit *ruleAudit) Check() error {
+	if audit.frameCt > audit.to || audit.matched {
+		return CheckEnded
+	}
+
+	if audit.frameCt < audit.rule.From {
+		return nil
+	}
+
+	if audit.vcs.Mem.LastCPUWrite && audit.vcs.Mem.LastCPUAddressMapped == audit.addr {
+		if audit.vcs.Mem.LastCPUData&audit.mask == audit.value {
+			audit.matched = true
+			audit.matchFrame = audit.frameCt
+			audit.snapshot(audit.rule.Finding)
+		}
+	}
+
+	return nil
+}
+
+// Finalise implements the Audit interface
+func (audit *ruleAudit) Finalise(_ *strings.Builder) error {
+	if audit.matched {
+		return Finding{
+			Severity: audit.severity,
+			Msg:      fmt.Sprintf("%s (frame %d)", audit.rule.Finding, audit.matchFrame),
+		}
+	}
+	return FinalisedOk
+}
+
+// NewFrame implements the television.FrameTrigger() interface
+func (audit *ruleAudit) NewFrame(frameInfo frameinfo.Current) error {
+	audit.frameCt++
+	return nil
+}
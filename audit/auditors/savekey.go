@@ -0,0 +1,349 @@
+package auditors
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/environment"
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cpubus"
+	"github.com/jetsetilly/gopher2600/hardware/riot/ports"
+	"github.com/jetsetilly/gopher2600/hardware/riot/ports/plugging"
+	"github.com/jetsetilly/gopher2600/hardware/riot/ports/savekey"
+	"github.com/jetsetilly/gopher2600/hardware/television/frameinfo"
+)
+
+// the SWCHA bits used for the I2C lines by a peripheral in the right port
+const (
+	saveKeySDA = 0x04
+	saveKeySCL = 0x08
+)
+
+// the size of an EEPROM page and the size of the EEPROM
+const (
+	saveKeyPageSize = 64
+	saveKeySize     = 0x8000
+)
+
+// the I2C address of the EEPROM. the lowest bit of the control byte is the
+// direction of the transfer
+const saveKeyControl = 0xa0
+
+// the part of an I2C transfer that the next byte belongs to
+type saveKeyPhase int
+
+const (
+	saveKeyIdle saveKeyPhase = iota
+	saveKeyControlByte
+	saveKeyAddressHi
+	saveKeyAddressLo
+	saveKeyWrite
+	saveKeyRead
+)
+
+// a single I2C transfer between the ROM and the EEPROM
+type saveKeyTransfer struct {
+	frame   int
+	write   bool
+	address uint16
+	bytes   int
+}
+
+// saveKey plugs a SaveKey into the right controller port and decodes the I2C
+// traffic from the writes made by the ROM to SWCHA and SWACNT. the EEPROM
+// pages that are read and written are reported
+//
+// the emulated SaveKey loads and saves the EEPROM image on disk only for the
+// main emulation. the auditor refuses to run in the main emulation so that
+// the user's EEPROM file is never changed by an audit
+type saveKey struct {
+	romHash
+
+	vcs     *hardware.VCS
+	frameCt int
+
+	// the peripheral in the right port before the SaveKey was plugged in. it
+	// is plugged back in by Detach()
+	previous ports.Peripheral
+
+	// the values last written to SWCHA and SWACNT
+	swcha  uint8
+	swacnt uint8
+
+	// state of the I2C lines as driven by the ROM. a line is high unless the
+	// ROM has set it as an output and written a zero
+	sda bool
+	scl bool
+
+	// the byte being clocked in and the number of clocks in the byte, including
+	// the acknowledge clock
+	phase   saveKeyPhase
+	bits    uint8
+	clocks  int
+	address uint16
+
+	// every transfer in the audit. the last transfer is the current transfer
+	// if the phase is saveKeyWrite or saveKeyRead
+	transfers []saveKeyTransfer
+
+	// pages read and written
+	read    map[int]bool
+	written map[int]bool
+
+	// parameters
+	frames int
+	trace  string
+}
+
+// ID implements the Audit interface
+func (audit *saveKey) ID() string {
+	return "SaveKey"
+}
+
+// Info implements the Describer interface
+func (audit *saveKey) Info() Info {
+	return Info{
+		Description: "EEPROM pages read and written using a SaveKey in the right port",
+		Version:     "1.0",
+		Category:    "peripheral",
+	}
+}
+
+// Params implements the Configurable interface
+func (audit *saveKey) Params() []Param {
+	return []Param{
+		{Name: framesParam, Help: "number of frames to run the ROM for", Value: &audit.frames},
+		{Name: "trace", Help: "directory in which to write every I2C transfer as <hash>.csv", Value: &audit.trace},
+	}
+}
+
+// Initialise implements the Audit interface
+func (audit *saveKey) Initialise(vcs *hardware.VCS) error {
+	audit.vcs = vcs
+	audit.vcs.TV.AddFrameTrigger(audit)
+
+	audit.read = make(map[int]bool)
+	audit.written = make(map[int]bool)
+	audit.sda = true
+	audit.scl = true
+
+	if vcs.Env.IsEmulation(environment.MainEmulation) {
+		return fmt.Errorf("the SaveKey can not be audited in the main emulation")
+	}
+
+	audit.previous = audit.vcs.RIOT.Ports.RightPlayer
+	return audit.vcs.RIOT.Ports.Plug(plugging.PortRight, savekey.NewSaveKey)
+}
+
+// Detach implements the Detacher interface
+func (audit *saveKey) Detach() error {
+	// nothing to restore if the SaveKey was never plugged in
+	if audit.previous == nil {
+		return nil
+	}
+	previous := audit.previous
+	audit.previous = nil
+
+	return audit.vcs.RIOT.Ports.Plug(plugging.PortRight,
+		func(_ *environment.Environment, _ plugging.PortID, _ ports.PeripheralBus) ports.Peripheral {
+			return previous
+		})
+}
+
+// Check implements the Audit interface
+func (audit *saveKey) Check() error {
+	if audit.frameCt > audit.frames {
+		return CheckEnded
+	}
+
+	if !audit.vcs.Mem.LastCPUWrite {
+		return nil
+	}
+
+	switch audit.vcs.Mem.LastCPUAddressMapped {
+	case cpubus.WriteAddressByRegister[cpubus.SWCHA]:
+		audit.swcha = audit.vcs.Mem.LastCPUData
+	case cpubus.WriteAddressByRegister[cpubus.SWACNT]:
+		audit.swacnt = audit.vcs.Mem.LastCPUData
+	default:
+		return nil
+	}
+
+	line := func(bit uint8) bool {
+		return audit.swacnt&bit == 0 || audit.swcha&bit != 0
+	}
+	audit.lines(line(saveKeySDA), line(saveKeySCL))
+
+	return nil
+}
+
+// lines is called with the state of the I2C lines whenever the ROM changes
+// either of them
+func (audit *saveKey) lines(sda bool, scl bool) {
+	switch {
+	case audit.scl && scl && audit.sda && !sda:
+		// start condition. also a repeated start during a transfer
+		audit.phase = saveKeyControlByte
+		audit.clocks = 0
+	case audit.scl && scl && !audit.sda && sda:
+		// stop condition
+		audit.phase = saveKeyIdle
+	case !audit.scl && scl:
+		audit.clock(sda)
+	}
+
+	audit.sda = sda
+	audit.scl = scl
+}
+
+// clock is called on the rising edge of SCL with the state of SDA
+func (audit *saveKey) clock(sda bool) {
+	if audit.phase == saveKeyIdle {
+		return
+	}
+
+	audit.clocks++
+	if audit.clocks <= 8 {
+		audit.bits <<= 1
+		if sda {
+			audit.bits |= 0x01
+		}
+		return
+	}
+
+	// the ninth clock is the acknowledge. the byte is complete
+	audit.clocks = 0
+
+	switch audit.phase {
+	case saveKeyControlByte:
+		if audit.bits&0xfe != saveKeyControl {
+			audit.phase = saveKeyIdle
+		} else if audit.bits&0x01 == 0x01 {
+			audit.phase = saveKeyRead
+			audit.transfers = append(audit.transfers, saveKeyTransfer{
+				frame:   audit.frameCt,
+				address: audit.address,
+			})
+		} else {
+			audit.phase = saveKeyAddressHi
+		}
+	case saveKeyAddressHi:
+		audit.address = uint16(audit.bits) << 8
+		audit.phase = saveKeyAddressLo
+	case saveKeyAddressLo:
+		audit.address = (audit.address | uint16(audit.bits)) % saveKeySize
+		audit.phase = saveKeyWrite
+
+		// a ROM reading the EEPROM first sets the address in this way and
+		// then sends a repeated start. the transfer is then recorded as a
+		// write of zero bytes
+		audit.transfers = append(audit.transfers, saveKeyTransfer{
+			frame:   audit.frameCt,
+			write:   true,
+			address: audit.address,
+		})
+	case saveKeyWrite:
+		audit.written[int(audit.address)/saveKeyPageSize] = true
+		audit.transfers[len(audit.transfers)-1].bytes++
+
+		// writes wrap around at the end of the page
+		page := audit.address &^ (saveKeyPageSize - 1)
+		audit.address = page | (audit.address+1)&(saveKeyPageSize-1)
+	case saveKeyRead:
+		audit.read[int(audit.address)/saveKeyPageSize] = true
+		audit.transfers[len(audit.transfers)-1].bytes++
+
+		// reads continue into the next page
+		audit.address = (audit.address + 1) % saveKeySize
+	}
+}
+
+// pageList describes the pages as a list of page numbers and ranges of page
+// numbers. for example, "0-3, 8"
+func pageList(pages map[int]bool) string {
+	var s []string
+	for p := 0; p < saveKeySize/saveKeyPageSize; p++ {
+		if !pages[p] {
+			continue
+		}
+		q := p
+		for pages[q+1] {
+			q++
+		}
+		if q == p {
+			s = append(s, strconv.Itoa(p))
+		} else {
+			s = append(s, fmt.Sprintf("%d-%d", p, q))
+		}
+		p = q
+	}
+	return strings.Join(s, ", ")
+}
+
+// Finalise implements the Audit interface
+func (audit *saveKey) Finalise(msg *strings.Builder) error {
+	if audit.trace != "" && len(audit.transfers) > 0 {
+		err := audit.writeTrace()
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(audit.read) == 0 && len(audit.written) == 0 {
+		msg.WriteString("no EEPROM access")
+		return FinalisedOk
+	}
+
+	var s []string
+	if len(audit.read) > 0 {
+		s = append(s, fmt.Sprintf("reads pages %s", pageList(audit.read)))
+	}
+	if len(audit.written) > 0 {
+		s = append(s, fmt.Sprintf("writes pages %s", pageList(audit.written)))
+	}
+	msg.WriteString(strings.Join(s, " | "))
+
+	return FinalisedOk
+}
+
+// writeTrace writes every I2C transfer to a CSV file in the trace directory.
+// the file is named after the hash of the ROM
+func (audit *saveKey) writeTrace() error {
+	fn, err := audit.filename(audit.trace, "csv")
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(fn)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"frame", "direction", "address", "page", "bytes"})
+	for _, t := range audit.transfers {
+		dir := "read"
+		if t.write {
+			dir = "write"
+		}
+		w.Write([]string{
+			strconv.Itoa(t.frame),
+			dir,
+			fmt.Sprintf("$%04x", t.address),
+			strconv.Itoa(int(t.address) / saveKeyPageSize),
+			strconv.Itoa(t.bytes),
+		})
+	}
+	w.Flush()
+
+	return w.Error()
+}
+
+// NewFrame implements the television.FrameTrigger() interface
+func (audit *saveKey) NewFrame(frameInfo frameinfo.Current) error {
+	audit.frameCt++
+	return nil
+}
@@ -0,0 +1,136 @@
+package auditors
+
+import (
+	"testing"
+)
+
+// i2cMaster drives the I2C decoder of the SaveKey auditor in the same way as a
+// ROM bit-banging the I2C lines
+type i2cMaster struct {
+	audit *saveKey
+}
+
+func newI2CMaster() *i2cMaster {
+	return &i2cMaster{
+		audit: &saveKey{
+			read:    make(map[int]bool),
+			written: make(map[int]bool),
+			sda:     true,
+			scl:     true,
+		},
+	}
+}
+
+func (m *i2cMaster) start() {
+	m.audit.lines(true, true)
+	m.audit.lines(false, true)
+	m.audit.lines(false, false)
+}
+
+func (m *i2cMaster) stop() {
+	m.audit.lines(false, false)
+	m.audit.lines(false, true)
+	m.audit.lines(true, true)
+}
+
+// byte clocks out eight bits followed by the acknowledge clock
+func (m *i2cMaster) byte(v uint8) {
+	for i := 7; i >= 0; i-- {
+		sda := v&(1<<i) != 0
+		m.audit.lines(sda, false)
+		m.audit.lines(sda, true)
+		m.audit.lines(sda, false)
+	}
+	m.audit.lines(true, false)
+	m.audit.lines(true, true)
+	m.audit.lines(true, false)
+}
+
+// address sets the EEPROM address with a write transfer of no bytes
+func (m *i2cMaster) address(addr uint16) {
+	m.start()
+	m.byte(saveKeyControl)
+	m.byte(uint8(addr >> 8))
+	m.byte(uint8(addr))
+}
+
+func TestSaveKeyWrite(t *testing.T) {
+	m := newI2CMaster()
+	m.address(0x3000)
+	m.byte(0x01)
+	m.byte(0x02)
+	m.stop()
+
+	if s := pageList(m.audit.written); s != "192" {
+		t.Errorf("expected pages written to be 192, got %q", s)
+	}
+	if len(m.audit.read) != 0 {
+		t.Errorf("unexpected pages read: %s", pageList(m.audit.read))
+	}
+	if len(m.audit.transfers) != 1 || m.audit.transfers[0].bytes != 2 {
+		t.Errorf("expected one transfer of two bytes, got %v", m.audit.transfers)
+	}
+	if m.audit.phase != saveKeyIdle {
+		t.Errorf("expected decoder to be idle after stop condition")
+	}
+}
+
+// writes wrap around to the start of the page
+func TestSaveKeyWriteWrap(t *testing.T) {
+	m := newI2CMaster()
+	m.address(0x303f)
+	m.byte(0x01)
+	m.byte(0x02)
+	m.stop()
+
+	if s := pageList(m.audit.written); s != "192" {
+		t.Errorf("expected pages written to be 192, got %q", s)
+	}
+	if m.audit.address != 0x3001 {
+		t.Errorf("expected address to wrap to $3001, got $%04x", m.audit.address)
+	}
+}
+
+// reads set the address with a write transfer and then continue after a
+// repeated start. reads continue into the next page
+func TestSaveKeyRead(t *testing.T) {
+	m := newI2CMaster()
+	m.address(0x303f)
+	m.start()
+	m.byte(saveKeyControl | 0x01)
+	m.byte(0xff)
+	m.byte(0xff)
+	m.stop()
+
+	if s := pageList(m.audit.read); s != "192-193" {
+		t.Errorf("expected pages read to be 192-193, got %q", s)
+	}
+	if len(m.audit.written) != 0 {
+		t.Errorf("unexpected pages written: %s", pageList(m.audit.written))
+	}
+	if len(m.audit.transfers) != 2 || m.audit.transfers[1].write || m.audit.transfers[1].bytes != 2 {
+		t.Errorf("expected a read transfer of two bytes, got %v", m.audit.transfers)
+	}
+}
+
+// transfers addressed to another device are ignored
+func TestSaveKeyOtherDevice(t *testing.T) {
+	m := newI2CMaster()
+	m.start()
+	m.byte(0x50)
+	m.byte(0x30)
+	m.byte(0x00)
+	m.byte(0x01)
+	m.stop()
+
+	if len(m.audit.read) != 0 || len(m.audit.written) != 0 || len(m.audit.transfers) != 0 {
+		t.Errorf("unexpected EEPROM access for another device")
+	}
+}
+
+func TestPageList(t *testing.T) {
+	pages := map[int]bool{0: true, 1: true, 2: true, 3: true, 8: true, 511: true}
+	if s := pageList(pages); s != "0-3, 8, 511" {
+		t.Errorf("unexpected page list: %q", s)
+	}
+}
@@ -0,0 +1,399 @@
+package auditors
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cpubus"
+	"github.com/jetsetilly/gopher2600/hardware/television/frameinfo"
+	"github.com/jetsetilly/gopher2600/hardware/television/signal"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// LoadScript compiles the Lua script in the named file and adds it to the
+// Factory. The ID of the auditor is taken from the global "id" variable in the
+// script or, if the script does not define one, from the name of the file. The
+// optional "description" and "version" variables are shown by the -list option
+//
+// Scripts implement the same lifecycle as the Audit interface by defining the
+// following global functions:
+//
+//	initialise()      optional. called once before the emulation starts
+//	check()           called after every CPU instruction. return true to end the check
+//	finalise()        return a string to report a finding and optionally "warning" as a
+//	                  second value. return nothing for okay
+//	new_frame(frame)  optional. called at the start of every television frame
+//	set_pixels(pix)   optional. called with the pixels of the television frame
+//
+// The set_pixels() function is passed an array of colour values with VBLANK
+// pixels represented by the value -1. Defining set_pixels() will slow the audit
+// considerably
+//
+// The state of the emulation is available through the vcs table:
+//
+//	vcs.cpu()          table of CPU registers: pc, a, x, y, sp, status
+//	vcs.instruction()  table of last CPU instruction: final, opcode, operator, address, cycles, undocumented
+//	vcs.bus()          table of last CPU bus access: address, literal, data, write
+//	vcs.frame()        table of television frame: number, stable, scanlines, top, bottom, vsync_count, refresh, spec
+//	vcs.coords()       table of television coordinates: frame, scanline, clock
+//	vcs.signal()       table of last television signal: vsync, vblank, hsync, color
+//	vcs.peek(addr)     value of memory at address
+//
+// The read and write tables map the names of chip registers (eg. "COLUBK") to
+// the addresses used by vcs.bus(). The message(s) function adds to the
+// message that is printed when the audit finalises okay. The snapshot(reason)
+// function requests a snapshot of the emulation if snapshots are being taken
+//
+// The check ends after the number of frames given by the "frames" parameter
+// even if the check() function has not returned true
+func LoadScript(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return fmt.Errorf("script: %w", err)
+	}
+
+	chunk, err := parse.Parse(strings.NewReader(string(data)), filename)
+	if err != nil {
+		return fmt.Errorf("script: %w", err)
+	}
+
+	proto, err := lua.Compile(chunk, filename)
+	if err != nil {
+		return fmt.Errorf("script: %w", err)
+	}
+
+	// run the script once to discover the ID and to make sure the required
+	// functions have been defined
+	aud := &scriptAudit{proto: proto}
+	err = aud.load()
+	if err != nil {
+		return fmt.Errorf("script: %s: %w", filename, err)
+	}
+	defer aud.state.Close()
+
+	id := filepath.Base(filename)
+	id = strings.TrimSuffix(id, filepath.Ext(id))
+	if s, ok := aud.state.GetGlobal("id").(lua.LString); ok {
+		id = string(s)
+	}
+	description := lua.LVAsString(aud.state.GetGlobal("description"))
+	version := lua.LVAsString(aud.state.GetGlobal("version"))
+
+	for _, fn := range []string{"check", "finalise"} {
+		if _, ok := aud.state.GetGlobal(fn).(*lua.LFunction); !ok {
+			return fmt.Errorf("script: %s: %s() function is not defined", filename, fn)
+		}
+	}
+
+	err = register(func() Audit {
+		return &scriptAudit{id: id, proto: proto, description: description, version: version, frames: defaultFrames}
+	})
+	if err != nil {
+		return fmt.Errorf("script: %s: %w", filename, err)
+	}
+
+	return nil
+}
+
+type scriptAudit struct {
+	snapshotRequest
+
+	vcs     *hardware.VCS
+	frameCt int
+	id      string
+	proto   *lua.FunctionProto
+	state   *lua.LState
+
+	// taken from the global "description" and "version" variables in the
+	// script. empty if the script does not define them
+	description string
+	version     string
+
+	// strings sent by the message() function in the script
+	msg strings.Builder
+
+	// parameters
+	frames int
+}
+
+// load creates a new Lua state and runs the compiled script in it. the state
+// is closed if the script fails to run
+func (audit *scriptAudit) load() error {
+	audit.state = lua.NewState()
+	audit.state.SetGlobal("message", audit.state.NewFunction(func(L *lua.LState) int {
+		audit.msg.WriteString(L.CheckString(1))
+		return 0
+	}))
+	audit.state.SetGlobal("snapshot", audit.state.NewFunction(func(L *lua.LState) int {
+		audit.snapshot(L.CheckString(1))
+		return 0
+	}))
+	audit.state.SetGlobal("read", registerTable(audit.state, cpubus.ReadAddressByRegister))
+	audit.state.SetGlobal("write", registerTable(audit.state, cpubus.WriteAddressByRegister))
+	audit.state.SetGlobal("vcs", audit.state.SetFuncs(audit.state.NewTable(), map[string]lua.LGFunction{
+		"cpu":         audit.cpu,
+		"instruction": audit.instruction,
+		"bus":         audit.bus,
+		"frame":       audit.frame,
+		"coords":      audit.coords,
+		"signal":      audit.signal,
+		"peek":        audit.peek,
+	}))
+
+	audit.state.Push(audit.state.NewFunctionFromProto(audit.proto))
+	err := audit.state.PCall(0, lua.MultRet, nil)
+	if err != nil {
+		audit.state.Close()
+		audit.state = nil
+		return err
+	}
+	return nil
+}
+
+// call the named global function if it has been defined by the script.
+// returns the first value returned by the function
+func (audit *scriptAudit) call(fn string, args ...lua.LValue) (lua.LValue, error) {
+	ret, err := audit.callN(fn, 1, args...)
+	if err != nil {
+		return lua.LNil, err
+	}
+	return ret[0], nil
+}
+
+// call the named global function if it has been defined by the script.
+// returns the first n values returned by the function
+func (audit *scriptAudit) callN(fn string, n int, args ...lua.LValue) ([]lua.LValue, error) {
+	ret := make([]lua.LValue, n)
+	for i := range ret {
+		ret[i] = lua.LNil
+	}
+
+	f, ok := audit.state.GetGlobal(fn).(*lua.LFunction)
+	if !ok {
+		return ret, nil
+	}
+	err := audit.state.CallByParam(lua.P{Fn: f, NRet: n, Protect: true}, args...)
+	if err != nil {
+		return ret, err
+	}
+	for i := range ret {
+		ret[i] = audit.state.Get(i - n)
+	}
+	audit.state.Pop(n)
+	return ret, nil
+}
+
+// ID implements the Audit interface
+func (audit *scriptAudit) ID() string {
+	return audit.id
+}
+
+// Info implements the Describer interface
+func (audit *scriptAudit) Info() Info {
+	return Info{
+		Description: audit.description,
+		Version:     audit.version,
+		Category:    "script",
+	}
+}
+
+// Params implements the Configurable interface
+func (audit *scriptAudit) Params() []Param {
+	return []Param{
+		{Name: framesParam, Help: "number of frames to run the ROM for", Value: &audit.frames},
+	}
+}
+
+// Initialise implements the Audit interface
+func (audit *scriptAudit) Initialise(vcs *hardware.VCS) error {
+	audit.vcs = vcs
+
+	err := audit.load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := audit.state.GetGlobal("set_pixels").(*lua.LFunction); ok {
+		audit.vcs.TV.AddPixelRenderer(audit)
+	} else {
+		audit.vcs.TV.AddFrameTrigger(audit)
+	}
+
+	_, err = audit.call("initialise")
+	return err
+}
+
+// Detach implements the Detacher interface
+func (audit *scriptAudit) Detach() error {
+	if audit.state != nil {
+		audit.state.Close()
+		audit.state = nil
+	}
+	return nil
+}
+
+// Check implements the Audit interface
+func (audit *scriptAudit) Check() error {
+	if audit.frameCt > audit.frames {
+		return CheckEnded
+	}
+
+	ret, err := audit.call("check")
+	if err != nil {
+		return err
+	}
+	if lua.LVAsBool(ret) {
+		return CheckEnded
+	}
+	return nil
+}
+
+// Finalise implements the Audit interface
+func (audit *scriptAudit) Finalise(msg *strings.Builder) error {
+	ret, err := audit.callN("finalise", 2)
+	if err != nil {
+		return err
+	}
+	if s, ok := ret[0].(lua.LString); ok {
+		severity := Error
+		if lua.LVAsString(ret[1]) == Warning.String() {
+			severity = Warning
+		}
+		return Finding{Severity: severity, Msg: string(s)}
+	}
+
+	msg.WriteString(audit.msg.String())
+	return FinalisedOk
+}
+
+// NewFrame implements the television.FrameTrigger() and
+// television.PixelRenderer() interfaces
+func (audit *scriptAudit) NewFrame(frameInfo frameinfo.Current) error {
+	audit.frameCt++
+	_, err := audit.call("new_frame", lua.LNumber(frameInfo.FrameNum))
+	return err
+}
+
+// NewScanline implements the television.PixelRenderer() interface
+func (audit *scriptAudit) NewScanline(scanline int) error {
+	return nil
+}
+
+// SetPixels implements the television.PixelRenderer() interface
+func (audit *scriptAudit) SetPixels(sig []signal.SignalAttributes, last int) error {
+	pix := audit.state.CreateTable(last+1, 0)
+	for i := 0; i <= last; i++ {
+		if sig[i].VBlank {
+			pix.RawSetInt(i+1, lua.LNumber(-1))
+		} else {
+			pix.RawSetInt(i+1, lua.LNumber(sig[i].Color))
+		}
+	}
+	_, err := audit.call("set_pixels", pix)
+	return err
+}
+
+// Reset implements the television.PixelRenderer() interface
+func (audit *scriptAudit) Reset() {
+}
+
+// EndRendering implements the television.PixelRenderer() interface
+func (audit *scriptAudit) EndRendering() error {
+	return nil
+}
+
+// registerTable creates a Lua table of register names and addresses
+func registerTable(L *lua.LState, registers map[cpubus.Register]uint16) *lua.LTable {
+	tab := L.NewTable()
+	for r, addr := range registers {
+		tab.RawSetString(string(r), lua.LNumber(addr))
+	}
+	return tab
+}
+
+func (audit *scriptAudit) cpu(L *lua.LState) int {
+	tab := L.NewTable()
+	tab.RawSetString("pc", lua.LNumber(audit.vcs.CPU.PC.Address()))
+	tab.RawSetString("a", lua.LNumber(audit.vcs.CPU.A.Value()))
+	tab.RawSetString("x", lua.LNumber(audit.vcs.CPU.X.Value()))
+	tab.RawSetString("y", lua.LNumber(audit.vcs.CPU.Y.Value()))
+	tab.RawSetString("sp", lua.LNumber(audit.vcs.CPU.SP.Value()))
+	tab.RawSetString("status", lua.LNumber(audit.vcs.CPU.Status.Value()))
+	L.Push(tab)
+	return 1
+}
+
+func (audit *scriptAudit) instruction(L *lua.LState) int {
+	res := audit.vcs.CPU.LastResult
+	tab := L.NewTable()
+	tab.RawSetString("final", lua.LBool(res.Final))
+	tab.RawSetString("address", lua.LNumber(res.Address))
+	tab.RawSetString("cycles", lua.LNumber(res.Cycles))
+	if res.Defn != nil {
+		tab.RawSetString("opcode", lua.LNumber(res.Defn.OpCode))
+		tab.RawSetString("operator", lua.LString(res.Defn.Operator.String()))
+		tab.RawSetString("undocumented", lua.LBool(res.Defn.Undocumented))
+	}
+	L.Push(tab)
+	return 1
+}
+
+func (audit *scriptAudit) bus(L *lua.LState) int {
+	tab := L.NewTable()
+	tab.RawSetString("address", lua.LNumber(audit.vcs.Mem.LastCPUAddressMapped))
+	tab.RawSetString("literal", lua.LNumber(audit.vcs.Mem.LastCPUAddressLiteral))
+	tab.RawSetString("data", lua.LNumber(audit.vcs.Mem.LastCPUData))
+	tab.RawSetString("write", lua.LBool(audit.vcs.Mem.LastCPUWrite))
+	L.Push(tab)
+	return 1
+}
+
+func (audit *scriptAudit) frame(L *lua.LState) int {
+	info := audit.vcs.TV.GetFrameInfo()
+	tab := L.NewTable()
+	tab.RawSetString("number", lua.LNumber(info.FrameNum))
+	tab.RawSetString("stable", lua.LBool(info.Stable))
+	tab.RawSetString("scanlines", lua.LNumber(info.TotalScanlines))
+	tab.RawSetString("top", lua.LNumber(info.VisibleTop))
+	tab.RawSetString("bottom", lua.LNumber(info.VisibleBottom))
+	tab.RawSetString("vsync_count", lua.LNumber(info.VSYNCcount))
+	tab.RawSetString("refresh", lua.LNumber(info.RefreshRate))
+	tab.RawSetString("spec", lua.LString(info.Spec.ID))
+	L.Push(tab)
+	return 1
+}
+
+func (audit *scriptAudit) coords(L *lua.LState) int {
+	c := audit.vcs.TV.GetCoords()
+	tab := L.NewTable()
+	tab.RawSetString("frame", lua.LNumber(c.Frame))
+	tab.RawSetString("scanline", lua.LNumber(c.Scanline))
+	tab.RawSetString("clock", lua.LNumber(c.Clock))
+	L.Push(tab)
+	return 1
+}
+
+func (audit *scriptAudit) signal(L *lua.LState) int {
+	sig := audit.vcs.TV.GetLastSignal()
+	tab := L.NewTable()
+	tab.RawSetString("vsync", lua.LBool(sig.VSync))
+	tab.RawSetString("vblank", lua.LBool(sig.VBlank))
+	tab.RawSetString("hsync", lua.LBool(sig.HSync))
+	tab.RawSetString("color", lua.LNumber(sig.Color))
+	L.Push(tab)
+	return 1
+}
+
+func (audit *scriptAudit) peek(L *lua.LState) int {
+	v, err := audit.vcs.Mem.Peek(uint16(L.CheckInt(1)))
+	if err != nil {
+		L.RaiseError("%s", err.Error())
+		return 0
+	}
+	L.Push(lua.LNumber(v))
+	return 1
+}
@@ -9,9 +9,15 @@ import (
 )
 
 type shortVsync struct {
+	snapshotRequest
+
 	vcs        *hardware.VCS
 	frameCt    int
 	shortVsync bool
+
+	// parameters
+	frames int
+	min    int
 }
 
 // ID implements the Audit interface
@@ -19,6 +25,23 @@ func (audit *shortVsync) ID() string {
 	return "ShortVsync"
 }
 
+// Info implements the Describer interface
+func (audit *shortVsync) Info() Info {
+	return Info{
+		Description: "VSYNC signals shorter than the minimum number of scanlines",
+		Version:     "1.0",
+		Category:    "video",
+	}
+}
+
+// Params implements the Configurable interface
+func (audit *shortVsync) Params() []Param {
+	return []Param{
+		{Name: framesParam, Help: "number of frames to run the ROM for", Value: &audit.frames},
+		{Name: "min", Help: "minimum number of VSYNC scanlines", Value: &audit.min},
+	}
+}
+
 // Initialise implements the Audit interface
 func (audit *shortVsync) Initialise(vcs *hardware.VCS) error {
 	audit.vcs = vcs
@@ -28,7 +51,7 @@ func (audit *shortVsync) Initialise(vcs *hardware.VCS) error {
 
 // Check implements the Audit interface
 func (audit *shortVsync) Check() error {
-	if audit.frameCt > 60 {
+	if audit.frameCt > audit.frames {
 		return CheckEnded
 	}
 	return nil
@@ -45,8 +68,9 @@ func (audit *shortVsync) Finalise(_ *strings.Builder) error {
 // NewFrame implements the television.FrameTrigger() interface
 func (audit *shortVsync) NewFrame(frameInfo frameinfo.Current) error {
 	audit.frameCt++
-	if frameInfo.Stable {
-		audit.shortVsync = audit.shortVsync || frameInfo.VSYNCcount < 3
+	if frameInfo.Stable && frameInfo.VSYNCcount < audit.min && !audit.shortVsync {
+		audit.shortVsync = true
+		audit.snapshot(fmt.Sprintf("VSYNC of %d scanlines", frameInfo.VSYNCcount))
 	}
 	return nil
 }
@@ -0,0 +1,31 @@
+package auditors
+
+// Snapshotter is implemented by auditors that can request a snapshot of the
+// emulation at the moment a finding is detected. SetSnapshot() is called before
+// Initialise() and only if snapshots have been requested by the user
+//
+// The snapshot function should be called from within Check() or from one of
+// the television interfaces so that the state of the emulation matches the
+// finding. The function returns immediately and the auditor continues normally
+type Snapshotter interface {
+	SetSnapshot(snapshot func(reason string))
+}
+
+// snapshotRequest can be embedded in an auditor to implement the Snapshotter
+// interface
+type snapshotRequest struct {
+	request func(reason string)
+}
+
+// SetSnapshot implements the Snapshotter interface
+func (s *snapshotRequest) SetSnapshot(snapshot func(reason string)) {
+	s.request = snapshot
+}
+
+// snapshot requests a snapshot of the emulation. does nothing if snapshots have
+// not been requested
+func (s *snapshotRequest) snapshot(reason string) {
+	if s.request != nil {
+		s.request(reason)
+	}
+}
@@ -0,0 +1,267 @@
+package auditors
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/hardware/television/frameinfo"
+	"github.com/jetsetilly/gopher2600/hardware/television/signal"
+	"github.com/jetsetilly/gopher2600/hardware/television/specification"
+)
+
+// the shape of the VSYNC and VBLANK signals in a single frame
+type syncFrame struct {
+	frame     int
+	stable    bool
+	scanlines int
+
+	// position and length of the first VSYNC signal in the frame. vsyncClks
+	// is zero if there is no VSYNC signal
+	vsyncScanline int
+	vsyncClock    int
+	vsyncClks     int
+
+	// VSYNC started after the end of HBLANK
+	vsyncMidLine bool
+
+	// VBLANK was on for the entire VSYNC signal
+	vblankCovers bool
+
+	// the scanline on which the VBLANK at the top of the frame ends and the
+	// scanline on which the VBLANK at the bottom of the frame starts. -1 if
+	// there is no such transition
+	vblankEnd   int
+	vblankStart int
+}
+
+// the values that are compared between frames to decide whether the shape of
+// the sync signals varies
+func (f syncFrame) shape() [4]int {
+	return [4]int{f.vsyncClks, f.vsyncClock, f.vblankEnd, f.vblankStart}
+}
+
+// syncShape measures the VSYNC and VBLANK signals of every frame
+type syncShape struct {
+	romHash
+
+	vcs     *hardware.VCS
+	frameCt int
+
+	frames []syncFrame
+
+	// parameters
+	maxFrames int
+	timeline  string
+}
+
+// ID implements the Audit interface
+func (audit *syncShape) ID() string {
+	return "SyncShape"
+}
+
+// Info implements the Describer interface
+func (audit *syncShape) Info() Info {
+	return Info{
+		Description: "Shape of the VSYNC and VBLANK signals in each frame",
+		Version:     "1.0",
+		Category:    "video",
+	}
+}
+
+// Params implements the Configurable interface
+func (audit *syncShape) Params() []Param {
+	return []Param{
+		{Name: framesParam, Help: "number of frames to run the ROM for", Value: &audit.maxFrames},
+		{Name: "timeline", Help: "directory in which to write the per-frame sync timeline as <hash>.csv", Value: &audit.timeline},
+	}
+}
+
+// Initialise implements the Audit interface
+func (audit *syncShape) Initialise(vcs *hardware.VCS) error {
+	audit.vcs = vcs
+	audit.vcs.TV.AddPixelRenderer(audit)
+	return nil
+}
+
+// Check implements the Audit interface
+func (audit *syncShape) Check() error {
+	if audit.frameCt > audit.maxFrames {
+		return CheckEnded
+	}
+	return nil
+}
+
+// Finalise implements the Audit interface
+func (audit *syncShape) Finalise(msg *strings.Builder) error {
+	if audit.timeline != "" {
+		err := audit.writeTimeline()
+		if err != nil {
+			return err
+		}
+	}
+
+	var stable []syncFrame
+	for _, f := range audit.frames {
+		if f.stable {
+			stable = append(stable, f)
+		}
+	}
+	if len(stable) == 0 {
+		return Warningf("no stable frames")
+	}
+
+	var findings []string
+	var noVSYNC, midLine, uncovered int
+	shapes := make(map[[4]int]bool)
+	for _, f := range stable {
+		if f.vsyncClks == 0 {
+			noVSYNC++
+			continue
+		}
+		if f.vsyncMidLine {
+			midLine++
+		}
+		if !f.vblankCovers {
+			uncovered++
+		}
+		shapes[f.shape()] = true
+	}
+
+	if noVSYNC > 0 {
+		findings = append(findings, fmt.Sprintf("no VSYNC in %d of %d frames", noVSYNC, len(stable)))
+	}
+	if midLine > 0 {
+		findings = append(findings, fmt.Sprintf("VSYNC starts mid-line in %d of %d frames", midLine, len(stable)))
+	}
+	if uncovered > 0 {
+		findings = append(findings, fmt.Sprintf("VBLANK does not cover VSYNC in %d of %d frames", uncovered, len(stable)))
+	}
+	if len(shapes) > 1 {
+		findings = append(findings, fmt.Sprintf("sync shape varies between frames (%d variations)", len(shapes)))
+	}
+
+	// the summary is for the last stable frame
+	f := stable[len(stable)-1]
+	summary := fmt.Sprintf("VSYNC %.2f lines (%d clks) at %d:%d | VBLANK ends %d starts %d | %d scanlines",
+		float64(f.vsyncClks)/specification.ClksScanline,
+		f.vsyncClks, f.vsyncScanline, f.vsyncClock, f.vblankEnd, f.vblankStart, f.scanlines)
+
+	if len(findings) > 0 {
+		return Warningf("%s | %s", strings.Join(findings, " | "), summary)
+	}
+
+	msg.WriteString(summary)
+	return FinalisedOk
+}
+
+// writeTimeline writes the shape of every frame to a CSV file in the timeline
+// directory. the file is named after the hash of the ROM
+func (audit *syncShape) writeTimeline() error {
+	fn, err := audit.filename(audit.timeline, "csv")
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(fn)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Write([]string{"frame", "stable", "scanlines", "vsync_scanline", "vsync_clock", "vsync_clks",
+		"vsync_midline", "vblank_covers_vsync", "vblank_end", "vblank_start"})
+	for _, s := range audit.frames {
+		w.Write([]string{
+			strconv.Itoa(s.frame),
+			strconv.FormatBool(s.stable),
+			strconv.Itoa(s.scanlines),
+			strconv.Itoa(s.vsyncScanline),
+			strconv.Itoa(s.vsyncClock),
+			strconv.Itoa(s.vsyncClks),
+			strconv.FormatBool(s.vsyncMidLine),
+			strconv.FormatBool(s.vblankCovers),
+			strconv.Itoa(s.vblankEnd),
+			strconv.Itoa(s.vblankStart),
+		})
+	}
+	w.Flush()
+
+	return w.Error()
+}
+
+// NewFrame implements the television.PixelRenderer() interface
+func (audit *syncShape) NewFrame(frameInfo frameinfo.Current) error {
+	audit.frameCt++
+	return nil
+}
+
+// NewScanline implements the television.PixelRenderer() interface
+func (audit *syncShape) NewScanline(scanline int) error {
+	return nil
+}
+
+// SetPixels implements the television.PixelRenderer() interface
+func (audit *syncShape) SetPixels(sig []signal.SignalAttributes, last int) error {
+	f := syncFrame{
+		frame:        audit.frameCt,
+		stable:       audit.vcs.TV.GetFrameInfo().Stable,
+		vblankCovers: true,
+		vblankEnd:    -1,
+		vblankStart:  -1,
+	}
+
+	inVSYNC := false
+	vsyncDone := false
+	prevVBlank := true
+
+	for i := 0; i <= last && i < len(sig); i++ {
+		s := sig[i]
+		if s.Index == signal.NoSignal {
+			continue
+		}
+
+		scanline := s.Index / specification.ClksScanline
+		clock := s.Index % specification.ClksScanline
+		f.scanlines = scanline + 1
+
+		// only the first VSYNC in the frame is measured
+		if s.VSync && !vsyncDone {
+			if !inVSYNC {
+				inVSYNC = true
+				f.vsyncScanline = scanline
+				f.vsyncClock = clock
+				f.vsyncMidLine = clock >= specification.ClksHBlank
+			}
+			f.vsyncClks++
+			f.vblankCovers = f.vblankCovers && s.VBlank
+		} else if inVSYNC {
+			inVSYNC = false
+			vsyncDone = true
+		}
+
+		if prevVBlank && !s.VBlank && f.vblankEnd == -1 {
+			f.vblankEnd = scanline
+		}
+		if !prevVBlank && s.VBlank {
+			f.vblankStart = scanline
+		}
+		prevVBlank = s.VBlank
+	}
+
+	audit.frames = append(audit.frames, f)
+
+	return nil
+}
+
+// Reset implements the television.PixelRenderer() interface
+func (audit *syncShape) Reset() {
+}
+
+// EndRendering implements the television.PixelRenderer() interface
+func (audit *syncShape) EndRendering() error {
+	return nil
+}
@@ -0,0 +1,184 @@
+package auditors
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/hardware/television/frameinfo"
+	"github.com/jetsetilly/gopher2600/hardware/television/signal"
+	"github.com/jetsetilly/gopher2600/hardware/television/specification"
+)
+
+// the scanlines drawn to in a single frame
+type drawnFrame struct {
+	top    int
+	bottom int
+}
+
+// visibleArea finds the first and last scanlines in each frame that contain
+// visible pixels and compares them against the safe area of the TV
+// specification
+type visibleArea struct {
+	vcs       *hardware.VCS
+	frameCt   int
+	frameInfo frameinfo.Current
+
+	frames []drawnFrame
+
+	// the safe area of the specification in use when the last frame was drawn
+	safeTop    int
+	safeBottom int
+
+	// parameters
+	maxFrames int
+	tolerance int
+}
+
+// ID implements the Audit interface
+func (audit *visibleArea) ID() string {
+	return "VisibleArea"
+}
+
+// Info implements the Describer interface
+func (audit *visibleArea) Info() Info {
+	return Info{
+		Description: "Drawing outside the safe visible area and visible windows that move between frames",
+		Version:     "1.0",
+		Category:    "video",
+	}
+}
+
+// Params implements the Configurable interface
+func (audit *visibleArea) Params() []Param {
+	return []Param{
+		{Name: framesParam, Help: "number of frames to run the ROM for", Value: &audit.maxFrames},
+		{Name: "tolerance", Help: "number of scanlines the visible window can move before it is reported", Value: &audit.tolerance},
+	}
+}
+
+// Initialise implements the Audit interface
+func (audit *visibleArea) Initialise(vcs *hardware.VCS) error {
+	audit.vcs = vcs
+	audit.vcs.TV.AddPixelRenderer(audit)
+	return nil
+}
+
+// Check implements the Audit interface
+func (audit *visibleArea) Check() error {
+	if audit.frameCt > audit.maxFrames {
+		return CheckEnded
+	}
+	return nil
+}
+
+// Finalise implements the Audit interface
+func (audit *visibleArea) Finalise(msg *strings.Builder) error {
+	if len(audit.frames) == 0 {
+		return Warningf("nothing drawn in any stable frame")
+	}
+
+	minTop, maxTop := audit.frames[0].top, audit.frames[0].top
+	minBottom, maxBottom := audit.frames[0].bottom, audit.frames[0].bottom
+	var overTop, overBottom int
+	for _, f := range audit.frames {
+		minTop = min(minTop, f.top)
+		maxTop = max(maxTop, f.top)
+		minBottom = min(minBottom, f.bottom)
+		maxBottom = max(maxBottom, f.bottom)
+		if f.top < audit.safeTop {
+			overTop++
+		}
+		if f.bottom > audit.safeBottom {
+			overBottom++
+		}
+	}
+
+	var findings []string
+	if overTop > 0 {
+		findings = append(findings, fmt.Sprintf("draws above safe area in %d of %d frames (line %d, safe %d)",
+			overTop, len(audit.frames), minTop, audit.safeTop))
+	}
+	if overBottom > 0 {
+		findings = append(findings, fmt.Sprintf("draws below safe area in %d of %d frames (line %d, safe %d)",
+			overBottom, len(audit.frames), maxBottom, audit.safeBottom))
+	}
+	if maxTop-minTop > audit.tolerance || maxBottom-minBottom > audit.tolerance {
+		findings = append(findings, fmt.Sprintf("visible window shifts between frames (top %d-%d, bottom %d-%d)",
+			minTop, maxTop, minBottom, maxBottom))
+	}
+
+	// the summary is for the last frame
+	f := audit.frames[len(audit.frames)-1]
+	summary := fmt.Sprintf("drawn %d to %d | safe %d to %d", f.top, f.bottom, audit.safeTop, audit.safeBottom)
+
+	if len(findings) > 0 {
+		return Warningf("%s | %s", strings.Join(findings, " | "), summary)
+	}
+
+	msg.WriteString(summary)
+	return FinalisedOk
+}
+
+// NewFrame implements the television.PixelRenderer() interface
+func (audit *visibleArea) NewFrame(frameInfo frameinfo.Current) error {
+	audit.frameCt++
+	audit.frameInfo = frameInfo
+	return nil
+}
+
+// NewScanline implements the television.PixelRenderer() interface
+func (audit *visibleArea) NewScanline(scanline int) error {
+	return nil
+}
+
+// SetPixels implements the television.PixelRenderer() interface
+func (audit *visibleArea) SetPixels(sig []signal.SignalAttributes, last int) error {
+	if !audit.frameInfo.Stable {
+		return nil
+	}
+
+	f := drawnFrame{top: -1, bottom: -1}
+
+	for i := 0; i <= last && i < len(sig); i++ {
+		s := sig[i]
+		if s.Index == signal.NoSignal {
+			continue
+		}
+
+		// pixels in the horizontal blank are never seen
+		if s.Index%specification.ClksScanline < specification.ClksHBlank {
+			continue
+		}
+
+		if s.VBlank || s.Color == signal.VideoBlack {
+			continue
+		}
+
+		scanline := s.Index / specification.ClksScanline
+		if f.top == -1 {
+			f.top = scanline
+		}
+		f.bottom = scanline
+	}
+
+	// frames with nothing drawn in them say nothing about the visible area
+	if f.top == -1 {
+		return nil
+	}
+
+	audit.safeTop = audit.frameInfo.Spec.NewSafeVisibleTop
+	audit.safeBottom = audit.frameInfo.Spec.NewSafeVisibleBottom
+	audit.frames = append(audit.frames, f)
+
+	return nil
+}
+
+// Reset implements the television.PixelRenderer() interface
+func (audit *visibleArea) Reset() {
+}
+
+// EndRendering implements the television.PixelRenderer() interface
+func (audit *visibleArea) EndRendering() error {
+	return nil
+}
@@ -1,7 +1,6 @@
 package auditors
 
 import (
-	"fmt"
 	"strings"
 
 	"github.com/jetsetilly/gopher2600/hardware"
@@ -9,9 +8,16 @@ import (
 )
 
 type vsyncWithoutVblank struct {
-	vcs        *hardware.VCS
-	frameCt    int
-	usesVBLANK bool
+	snapshotRequest
+
+	vcs     *hardware.VCS
+	frameCt int
+
+	// set if any VSYNC signal in a stable frame is not accompanied by VBLANK
+	withoutVBLANK bool
+
+	// parameters
+	frames int
 }
 
 // ID implements the Audit interface
@@ -19,6 +25,22 @@ func (audit *vsyncWithoutVblank) ID() string {
 	return "VsyncWithoutVblank"
 }
 
+// Info implements the Describer interface
+func (audit *vsyncWithoutVblank) Info() Info {
+	return Info{
+		Description: "Frames generated with VSYNC but not VBLANK",
+		Version:     "1.0",
+		Category:    "video",
+	}
+}
+
+// Params implements the Configurable interface
+func (audit *vsyncWithoutVblank) Params() []Param {
+	return []Param{
+		{Name: framesParam, Help: "number of frames to run the ROM for", Value: &audit.frames},
+	}
+}
+
 // Initialise implements the Audit interface
 func (audit *vsyncWithoutVblank) Initialise(vcs *hardware.VCS) error {
 	audit.vcs = vcs
@@ -28,7 +50,7 @@ func (audit *vsyncWithoutVblank) Initialise(vcs *hardware.VCS) error {
 
 // Check implements the Audit interface
 func (audit *vsyncWithoutVblank) Check() error {
-	if audit.frameCt > 60 {
+	if audit.frameCt > audit.frames {
 		return CheckEnded
 	}
 
@@ -37,14 +59,17 @@ func (audit *vsyncWithoutVblank) Check() error {
 	}
 
 	sig := audit.vcs.TV.GetLastSignal()
-	audit.usesVBLANK = sig.VSync && sig.VBlank
+	if sig.VSync && !sig.VBlank && !audit.withoutVBLANK {
+		audit.withoutVBLANK = true
+		audit.snapshot("VSYNC without VBLANK")
+	}
 	return nil
 }
 
 // Finalise implements the Audit interface
 func (audit *vsyncWithoutVblank) Finalise(_ *strings.Builder) error {
-	if !audit.usesVBLANK {
-		return fmt.Errorf("ROM uses VSYNC without VBLANK")
+	if audit.withoutVBLANK {
+		return Warningf("ROM uses VSYNC without VBLANK")
 	}
 	return FinalisedOk
 }
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// the amount of emulation performed for a single ROM
+type benchSample struct {
+	worker   int
+	mapper   string
+	duration time.Duration
+	frames   int
+	cycles   int
+}
+
+// benchmark collects a sample for every ROM audited. used by the -bench mode
+// to report the throughput of the emulator
+type benchmark struct {
+	crit    sync.Mutex
+	samples []benchSample
+
+	// the time spent by the worker pool across all audit paths
+	wall time.Duration
+}
+
+// add a sample to the benchmark. safe to call from more than one goroutine
+func (bch *benchmark) add(s benchSample) {
+	bch.crit.Lock()
+	defer bch.crit.Unlock()
+	bch.samples = append(bch.samples, s)
+}
+
+// the throughput of a group of samples
+type throughput struct {
+	roms     int
+	frames   int
+	cycles   int
+	duration time.Duration
+}
+
+func (t *throughput) add(s benchSample) {
+	t.roms++
+	t.frames += s.frames
+	t.cycles += s.cycles
+	t.duration += s.duration
+}
+
+// write the throughput as a line in the tabwriter
+func (t throughput) write(w io.Writer, label string) {
+	secs := t.duration.Seconds()
+	if secs == 0 {
+		fmt.Fprintf(w, "%s\t%d\t-\t-\t-\t\n", label, t.roms)
+		return
+	}
+	fmt.Fprintf(w, "%s\t%d\t%.1f\t%.0f\t%.1f\t\n", label, t.roms,
+		float64(t.frames)/secs, float64(t.cycles)/secs, float64(t.roms)/secs*60)
+}
+
+// report writes the throughput of each worker and of each mapper type. the
+// aggregate throughput is measured against the wall time of the audit and so
+// includes the effect of running workers concurrently
+func (bch *benchmark) report(w io.Writer) {
+	bch.crit.Lock()
+	defer bch.crit.Unlock()
+
+	workers := make(map[int]*throughput)
+	mappers := make(map[string]*throughput)
+	var all throughput
+
+	for _, s := range bch.samples {
+		if _, ok := workers[s.worker]; !ok {
+			workers[s.worker] = &throughput{}
+		}
+		workers[s.worker].add(s)
+
+		if _, ok := mappers[s.mapper]; !ok {
+			mappers[s.mapper] = &throughput{}
+		}
+		mappers[s.mapper].add(s)
+
+		all.add(s)
+	}
+	all.duration = bch.wall
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	defer tw.Flush()
+
+	fmt.Fprintf(tw, "worker\tROMs\tframes/s\tcycles/s\tROMs/min\t\n")
+	var ids []int
+	for id := range workers {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		workers[id].write(tw, fmt.Sprintf("%d", id))
+	}
+	all.write(tw, "all")
+
+	fmt.Fprintf(tw, "\nmapper\tROMs\tframes/s\tcycles/s\tROMs/min\t\n")
+	var ms []string
+	for m := range mappers {
+		ms = append(ms, m)
+	}
+	sort.Strings(ms)
+	for _, m := range ms {
+		mappers[m].write(tw, m)
+	}
+}
+
+// profile the function and write the CPU and heap profiles to cpu.profile and
+// mem.profile in the current directory. the heap profile is written after the
+// function has returned
+func profile(runFunc func()) error {
+	cpuf, err := os.Create("cpu.profile")
+	if err != nil {
+		return fmt.Errorf("profile: %w", err)
+	}
+	defer cpuf.Close()
+
+	err = pprof.StartCPUProfile(cpuf)
+	if err != nil {
+		return fmt.Errorf("profile: %w", err)
+	}
+	runFunc()
+	pprof.StopCPUProfile()
+
+	memf, err := os.Create("mem.profile")
+	if err != nil {
+		return fmt.Errorf("profile: %w", err)
+	}
+	defer memf.Close()
+
+	runtime.GC()
+	err = pprof.WriteHeapProfile(memf)
+	if err != nil {
+		return fmt.Errorf("profile: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600-utils/audit/auditors"
+	"github.com/jetsetilly/gopher2600/cartridgeloader"
+	"github.com/jetsetilly/gopher2600/debugger/govern"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cpubus"
+	"github.com/jetsetilly/gopher2600/hardware/riot/ports/plugging"
+	"github.com/jetsetilly/gopher2600/hardware/television/signal"
+
+	_ "modernc.org/sqlite"
+)
+
+// the ID used for the results of the catalogue subcommand
+const catalogueAuditorID = "Catalogue"
+
+// the number of frames each ROM is run for when creating the catalogue
+const catalogueFrames = 60
+
+// sentinal error used to end the catalogue emulation after catalogueFrames
+var catalogueEnded = errors.New("catalogue ended")
+
+// the values of the catalogue format option
+const (
+	catalogueJSON   = "json"
+	catalogueSQLite = "sqlite"
+)
+
+// catalogue options. the catalogue subcommand uses the same walk of the audit
+// paths as the normal audit but creates a catalogueEntry for each ROM instead
+// of running the auditors
+type catalogue struct {
+	output string
+	format string
+
+	// directory in which to save the thumbnail of the first frame of each ROM.
+	// thumbnails are not saved if the directory is empty
+	thumbs string
+}
+
+// a single ROM in the catalogue
+type catalogueEntry struct {
+	Filename string `json:"filename"`
+	Hash     string `json:"md5"`
+	Size     int    `json:"size"`
+	Mapper   string `json:"mapper"`
+	Spec     string `json:"spec"`
+
+	// the controllers plugged into each port after fingerprinting the ROM
+	Left  string `json:"left"`
+	Right string `json:"right"`
+
+	// the input registers read by the ROM
+	Inputs []string `json:"inputs"`
+
+	// whether the ROM produces any sound
+	Audio bool `json:"audio"`
+
+	// path to the PNG image of the first frame. empty if thumbnails are not
+	// being saved
+	Thumbnail string `json:"thumbnail,omitempty"`
+}
+
+// the input registers that are noted by the catalogue
+var catalogueInputs = []cpubus.Register{
+	cpubus.SWCHA,
+	cpubus.INPT0,
+	cpubus.INPT1,
+	cpubus.INPT2,
+	cpubus.INPT3,
+	cpubus.INPT4,
+	cpubus.INPT5,
+}
+
+// audioActivity implements the television.AudioMixer interface and notes
+// whether either audio channel has a non-zero output
+type audioActivity struct {
+	active bool
+}
+
+// SetAudio implements the television.AudioMixer interface
+func (mix *audioActivity) SetAudio(sig []signal.AudioSignalAttributes) error {
+	for _, s := range sig {
+		if s.AudioChannel0 > 0 || s.AudioChannel1 > 0 {
+			mix.active = true
+			return nil
+		}
+	}
+	return nil
+}
+
+// EndMixing implements the television.AudioMixer interface
+func (mix *audioActivity) EndMixing() error {
+	return nil
+}
+
+// Reset implements the television.AudioMixer interface
+func (mix *audioActivity) Reset() {
+}
+
+// catalogueROM runs the ROM and creates the catalogue entry for it
+func (aud *audit) catalogueROM(ctx context.Context, emus emulations, loader cartridgeloader.Loader, rom *romResult, spec string) result {
+	res := result{auditor: catalogueAuditorID}
+
+	fail := func(err error) result {
+		res.severity = auditors.Error
+		res.msg = err.Error()
+		return res
+	}
+
+	emu, err := emus.get(spec)
+	if err != nil {
+		return fail(err)
+	}
+
+	err = emu.attach(loader, aud.startup, true)
+	if err != nil {
+		return fail(err)
+	}
+
+	mix := &audioActivity{}
+	emu.tv.AddAudioMixer(mix)
+	defer emu.tv.RemoveAudioMixer(mix)
+
+	// the thumbnail renderer is removed after the first frame
+	thmb := &thumbnail{}
+	emu.tv.AddPixelRenderer(thmb)
+	defer emu.tv.RemovePixelRenderer(thmb)
+
+	inputs := make(map[uint16]cpubus.Register)
+	for _, r := range catalogueInputs {
+		inputs[cpubus.ReadAddressByRegister[r]] = r
+	}
+	read := make(map[cpubus.Register]bool)
+
+	vcs := emu.vcs
+	err = vcs.Run(func() (govern.State, error) {
+		emu.cycles += vcs.CPU.LastResult.Cycles
+		if err := ctx.Err(); err != nil {
+			return govern.Ending, err
+		}
+
+		fn := emu.tv.GetFrameInfo().FrameNum
+		if fn > catalogueFrames {
+			return govern.Ending, catalogueEnded
+		}
+		if fn > 1 && thmb != nil {
+			emu.tv.RemovePixelRenderer(thmb)
+			rom.thumbnail = thmb.img
+			thmb = nil
+		}
+
+		if !vcs.Mem.LastCPUWrite {
+			if r, ok := inputs[vcs.Mem.LastCPUAddressMapped]; ok {
+				read[r] = true
+			}
+		}
+		return govern.Running, nil
+	})
+	emu.frames += emu.tv.GetCoords().Frame
+	if err != nil && !errors.Is(err, catalogueEnded) {
+		return fail(err)
+	}
+
+	entry := &catalogueEntry{
+		Filename: rom.filename,
+		Hash:     rom.hash,
+		Size:     rom.size,
+		Mapper:   vcs.Mem.Cart.ID(),
+		Spec:     emu.tv.GetFrameInfo().Spec.ID,
+		Left:     string(vcs.RIOT.Ports.PeripheralID(plugging.PortLeft)),
+		Right:    string(vcs.RIOT.Ports.PeripheralID(plugging.PortRight)),
+		Inputs:   []string{},
+		Audio:    mix.active,
+	}
+	for _, r := range catalogueInputs {
+		if read[r] {
+			entry.Inputs = append(entry.Inputs, string(r))
+		}
+	}
+
+	rom.mapper = entry.Mapper
+	rom.spec = entry.Spec
+	rom.controller = entry.Left
+	rom.catalogue = entry
+
+	if aud.catalogue.thumbs != "" && rom.thumbnail != nil {
+		entry.Thumbnail = filepath.Join(aud.catalogue.thumbs, fmt.Sprintf("%s.png", rom.hash))
+		err = writePNG(entry.Thumbnail, rom.thumbnail)
+		if err != nil {
+			return fail(fmt.Errorf("catalogue: %w", err))
+		}
+	}
+
+	res.msg = fmt.Sprintf("%s | %s | %s/%s | inputs: %s | audio: %v", entry.Mapper, entry.Spec,
+		entry.Left, entry.Right, strings.Join(entry.Inputs, ","), entry.Audio)
+
+	return res
+}
+
+// catalogueFormat decides the format of the catalogue from the format option
+// or from the extension of the output file if the format option is empty
+func catalogueFormat(format string, output string) (string, error) {
+	format = strings.ToLower(format)
+	switch format {
+	case catalogueJSON, catalogueSQLite:
+		return format, nil
+	case "":
+		switch strings.ToLower(filepath.Ext(output)) {
+		case ".db", ".sqlite", ".sqlite3":
+			return catalogueSQLite, nil
+		}
+		return catalogueJSON, nil
+	}
+	return "", fmt.Errorf("catalogue: unsupported format: %s", format)
+}
+
+// writeCatalogue writes every catalogued ROM to the output file
+func (aud *audit) writeCatalogue() error {
+	var entries []*catalogueEntry
+	for _, rom := range aud.results {
+		if rom.catalogue != nil {
+			entries = append(entries, rom.catalogue)
+		}
+	}
+	slices.SortFunc(entries, func(a, b *catalogueEntry) int {
+		return strings.Compare(a.Filename, b.Filename)
+	})
+
+	switch aud.catalogue.format {
+	case catalogueSQLite:
+		return writeCatalogueSQLite(aud.catalogue.output, entries)
+	}
+
+	data, err := json.MarshalIndent(entries, "", "\t")
+	if err != nil {
+		return fmt.Errorf("catalogue: %w", err)
+	}
+
+	err = os.WriteFile(aud.catalogue.output, data, 0644)
+	if err != nil {
+		return fmt.Errorf("catalogue: %w", err)
+	}
+
+	return nil
+}
+
+// the SQLite catalogue has a single table. ROMs are keyed by MD5 hash so an
+// existing catalogue can be added to or updated
+const catalogueSchema = `CREATE TABLE IF NOT EXISTS roms (
+	md5 TEXT PRIMARY KEY,
+	filename TEXT,
+	size INTEGER,
+	mapper TEXT,
+	spec TEXT,
+	left TEXT,
+	right TEXT,
+	inputs TEXT,
+	audio INTEGER,
+	thumbnail TEXT
+)`
+
+// writeCatalogueSQLite writes the entries to the named SQLite database
+func writeCatalogueSQLite(filename string, entries []*catalogueEntry) error {
+	db, err := sql.Open("sqlite", filename)
+	if err != nil {
+		return fmt.Errorf("catalogue: %w", err)
+	}
+	defer db.Close()
+
+	_, err = db.Exec(catalogueSchema)
+	if err != nil {
+		return fmt.Errorf("catalogue: %w", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("catalogue: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, e := range entries {
+		_, err = tx.Exec(`INSERT OR REPLACE INTO roms
+			(md5, filename, size, mapper, spec, left, right, inputs, audio, thumbnail)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			e.Hash, e.Filename, e.Size, e.Mapper, e.Spec, e.Left, e.Right,
+			strings.Join(e.Inputs, ","), e.Audio, e.Thumbnail)
+		if err != nil {
+			return fmt.Errorf("catalogue: %w", err)
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return fmt.Errorf("catalogue: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// the ways in which the result of an auditor can change between two runs
+type difference struct {
+	filename string
+	auditor  string
+	old      savedResult
+	new      savedResult
+}
+
+// the result of comparing two saved runs
+type runDiff struct {
+	added       []savedROM
+	removed     []savedROM
+	newFailures []difference
+	fixed       []difference
+	changed     []difference
+
+	// auditors that were run in the old run but not in the new run
+	removedAuditors []difference
+}
+
+// compareRuns compares two saved runs by ROM hash and auditor ID. the after
+// run is considered to be the more recent
+func compareRuns(before, after savedRun) runDiff {
+	var d runDiff
+
+	oldROMs := make(map[string]savedROM)
+	for _, rom := range before.ROMs {
+		oldROMs[rom.Hash] = rom
+	}
+
+	newROMs := make(map[string]savedROM)
+	for _, rom := range after.ROMs {
+		newROMs[rom.Hash] = rom
+	}
+
+	for _, rom := range before.ROMs {
+		if _, ok := newROMs[rom.Hash]; !ok {
+			d.removed = append(d.removed, rom)
+		}
+	}
+
+	for _, rom := range after.ROMs {
+		o, ok := oldROMs[rom.Hash]
+		if !ok {
+			d.added = append(d.added, rom)
+			continue
+		}
+
+		oldResults := make(map[string]savedResult)
+		for _, res := range o.Results {
+			oldResults[res.Auditor] = res
+		}
+
+		newResults := make(map[string]bool)
+		for _, res := range rom.Results {
+			newResults[res.Auditor] = true
+		}
+
+		for _, res := range o.Results {
+			if !newResults[res.Auditor] {
+				d.removedAuditors = append(d.removedAuditors, difference{
+					filename: rom.Filename,
+					auditor:  res.Auditor,
+					old:      res,
+				})
+			}
+		}
+
+		for _, res := range rom.Results {
+			diff := difference{
+				filename: rom.Filename,
+				auditor:  res.Auditor,
+				new:      res,
+			}
+
+			// an auditor that wasn't run in the old run is only of interest if
+			// it is now failing
+			or, ok := oldResults[res.Auditor]
+			if !ok {
+				if !res.Ok {
+					d.newFailures = append(d.newFailures, diff)
+				}
+				continue
+			}
+			diff.old = or
+
+			switch {
+			case or.Ok && !res.Ok:
+				d.newFailures = append(d.newFailures, diff)
+			case !or.Ok && res.Ok:
+				d.fixed = append(d.fixed, diff)
+			case or.Msg != res.Msg || or.Severity != res.Severity:
+				d.changed = append(d.changed, diff)
+			}
+		}
+	}
+
+	sortROMs := func(roms []savedROM) {
+		sort.Slice(roms, func(i, j int) bool {
+			return roms[i].Filename < roms[j].Filename
+		})
+	}
+	sortROMs(d.added)
+	sortROMs(d.removed)
+
+	sortDiffs := func(diffs []difference) {
+		sort.Slice(diffs, func(i, j int) bool {
+			if diffs[i].filename == diffs[j].filename {
+				return diffs[i].auditor < diffs[j].auditor
+			}
+			return diffs[i].filename < diffs[j].filename
+		})
+	}
+	sortDiffs(d.newFailures)
+	sortDiffs(d.fixed)
+	sortDiffs(d.changed)
+	sortDiffs(d.removedAuditors)
+
+	return d
+}
+
+// write a summary of the differences to the io.Writer
+func (d runDiff) write(w io.Writer) {
+	for _, rom := range d.added {
+		fmt.Fprintf(w, "added\t\t%s\t%s\n", rom.Filename, rom.Hash)
+	}
+	for _, rom := range d.removed {
+		fmt.Fprintf(w, "removed\t\t%s\t%s\n", rom.Filename, rom.Hash)
+	}
+	for _, diff := range d.newFailures {
+		fmt.Fprintf(w, "new failure\t%s\t%s\t%s\n", diff.filename, diff.auditor, diff.new.Msg)
+	}
+	for _, diff := range d.fixed {
+		fmt.Fprintf(w, "fixed\t\t%s\t%s\t%s\n", diff.filename, diff.auditor, diff.old.Msg)
+	}
+	for _, diff := range d.changed {
+		fmt.Fprintf(w, "changed\t\t%s\t%s\t%s -> %s\n", diff.filename, diff.auditor, diff.old.Msg, diff.new.Msg)
+	}
+	for _, diff := range d.removedAuditors {
+		fmt.Fprintf(w, "removed auditor\t%s\t%s\t%s\n", diff.filename, diff.auditor, diff.old.Msg)
+	}
+
+	fmt.Fprintf(w, "%d added, %d removed, %d new failures, %d fixed, %d changed, %d removed auditors\n",
+		len(d.added), len(d.removed), len(d.newFailures), len(d.fixed), len(d.changed), len(d.removedAuditors))
+}
+
+// diff implements the diff subcommand
+func diff(w io.Writer, args []string) error {
+	flgs := flag.NewFlagSet("Gopher2600-Audit diff", flag.ContinueOnError)
+	flgs.Usage = func() {
+		fmt.Fprintf(flgs.Output(), "usage: diff <old.json> <new.json>\n")
+	}
+
+	err := flgs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if flgs.NArg() != 2 {
+		flgs.Usage()
+		return fmt.Errorf("diff: requires two saved audit runs")
+	}
+
+	before, err := readRun(flgs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	after, err := readRun(flgs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	compareRuns(before, after).write(w)
+
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestCompareRuns(t *testing.T) {
+	okay := savedResult{Auditor: "HighHue", Ok: true, Severity: "okay", Msg: "ok"}
+	failed := savedResult{Auditor: "HighHue", Ok: false, Severity: "error", Msg: "hue $E used"}
+	changed := savedResult{Auditor: "HighHue", Ok: false, Severity: "warning", Msg: "hue $E used"}
+	other := savedResult{Auditor: "SyncShape", Ok: true, Severity: "okay", Msg: "ok"}
+
+	rom := func(hash string, results ...savedResult) savedROM {
+		return savedROM{Filename: hash + ".bin", Hash: hash, Results: results}
+	}
+
+	tests := []struct {
+		name            string
+		before          []savedROM
+		after           []savedROM
+		added           int
+		removed         int
+		newFailures     int
+		fixed           int
+		changed         int
+		removedAuditors int
+	}{
+		{
+			name:   "no differences",
+			before: []savedROM{rom("a", okay)},
+			after:  []savedROM{rom("a", okay)},
+		},
+		{
+			name:    "rom added and removed",
+			before:  []savedROM{rom("a", okay)},
+			after:   []savedROM{rom("b", okay)},
+			added:   1,
+			removed: 1,
+		},
+		{
+			name:        "new failure",
+			before:      []savedROM{rom("a", okay)},
+			after:       []savedROM{rom("a", failed)},
+			newFailures: 1,
+		},
+		{
+			name:   "fixed",
+			before: []savedROM{rom("a", failed)},
+			after:  []savedROM{rom("a", okay)},
+			fixed:  1,
+		},
+		{
+			name:    "changed severity",
+			before:  []savedROM{rom("a", failed)},
+			after:   []savedROM{rom("a", changed)},
+			changed: 1,
+		},
+		{
+			name:        "new auditor failing",
+			before:      []savedROM{rom("a", other)},
+			after:       []savedROM{rom("a", other, failed)},
+			newFailures: 1,
+		},
+		{
+			name:   "new auditor okay",
+			before: []savedROM{rom("a", other)},
+			after:  []savedROM{rom("a", other, okay)},
+		},
+		{
+			name:            "auditor removed",
+			before:          []savedROM{rom("a", other, failed)},
+			after:           []savedROM{rom("a", other)},
+			removedAuditors: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := compareRuns(savedRun{ROMs: tt.before}, savedRun{ROMs: tt.after})
+			if len(d.added) != tt.added {
+				t.Errorf("expected %d added, got %d", tt.added, len(d.added))
+			}
+			if len(d.removed) != tt.removed {
+				t.Errorf("expected %d removed, got %d", tt.removed, len(d.removed))
+			}
+			if len(d.newFailures) != tt.newFailures {
+				t.Errorf("expected %d new failures, got %d", tt.newFailures, len(d.newFailures))
+			}
+			if len(d.fixed) != tt.fixed {
+				t.Errorf("expected %d fixed, got %d", tt.fixed, len(d.fixed))
+			}
+			if len(d.changed) != tt.changed {
+				t.Errorf("expected %d changed, got %d", tt.changed, len(d.changed))
+			}
+			if len(d.removedAuditors) != tt.removedAuditors {
+				t.Errorf("expected %d removed auditors, got %d", tt.removedAuditors, len(d.removedAuditors))
+			}
+		})
+	}
+}
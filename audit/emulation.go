@@ -0,0 +1,152 @@
+package main
+
+import (
+	"log"
+
+	"github.com/jetsetilly/gopher2600-utils/audit/auditors"
+	"github.com/jetsetilly/gopher2600/cartridgeloader"
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/hardware/riot/ports"
+	"github.com/jetsetilly/gopher2600/hardware/riot/ports/plugging"
+	"github.com/jetsetilly/gopher2600/hardware/television"
+	"github.com/jetsetilly/gopher2600/recorder"
+)
+
+// emulation is a television and VCS that is reused for many audits. creating
+// a new television and VCS for every audit is expensive
+type emulation struct {
+	tv  *television.Television
+	vcs *hardware.VCS
+
+	// the number of frames and CPU cycles emulated over the lifetime of the
+	// emulation. used by the -bench mode
+	frames int
+	cycles int
+
+	// the playback recording that is attached to the VCS at the start of
+	// every audit. nil if there is no recording
+	recording *recording
+
+	// the playback of the recording once it has been attached to the VCS
+	playback *recorder.Playback
+}
+
+// newEmulation creates a new emulation with the requested tv specification.
+// normally this will be the auto-selecting specification
+func newEmulation(spec string) (*emulation, error) {
+	tv, err := television.NewTelevision(spec)
+	if err != nil {
+		return nil, err
+	}
+	tv.SetFPSCap(false)
+
+	vcs, err := hardware.NewVCS(auditors.Emulation, tv, nil, nil)
+	if err != nil {
+		tv.End()
+		return nil, err
+	}
+
+	return &emulation{
+		tv:  tv,
+		vcs: vcs,
+	}, nil
+}
+
+// attach the cartridge and reset the VCS ready for a new audit. the VCS will
+// be in the startup state with the colour switch set as specified
+func (emu *emulation) attach(loader cartridgeloader.Loader, st startup, colour bool) error {
+	st.prepare(emu.vcs)
+
+	err := emu.vcs.AttachCartridge(loader)
+	if err != nil {
+		return err
+	}
+
+	// the VCS may have been used for a previous audit so it must always be
+	// reset. resetting the VCS also resets the television
+	err = emu.vcs.Reset()
+	if err != nil {
+		return err
+	}
+
+	_, err = emu.vcs.RIOT.Ports.HandleInputEvent(ports.InputEvent{
+		Port: plugging.PortPanel,
+		Ev:   ports.PanelSetColor,
+		D:    colour,
+	})
+	if err != nil {
+		return err
+	}
+
+	err = st.apply(emu.vcs)
+	if err != nil {
+		return err
+	}
+
+	if emu.recording != nil {
+		return emu.attachRecording(*emu.recording)
+	}
+
+	return nil
+}
+
+// detach removes the auditor from the television. auditors add themselves to
+// the television in the Initialise() function and must be removed before the
+// emulation is reused. any other changes made by the auditor are undone by
+// the auditor itself if it implements the auditors.Detacher interface
+func (emu *emulation) detach(audit auditors.Audit) {
+	if d, ok := audit.(auditors.Detacher); ok {
+		if err := d.Detach(); err != nil {
+			log.Printf("detach: %s: %v", audit.ID(), err)
+		}
+	}
+	if r, ok := audit.(television.PixelRenderer); ok {
+		emu.tv.RemovePixelRenderer(r)
+	}
+	if f, ok := audit.(television.FrameTrigger); ok {
+		emu.tv.RemoveFrameTrigger(f)
+	}
+	if f, ok := audit.(television.ScanlineTrigger); ok {
+		emu.tv.RemoveScanlineTrigger(f)
+	}
+	if m, ok := audit.(television.AudioMixer); ok {
+		emu.tv.RemoveAudioMixer(m)
+	}
+}
+
+// emulations are keyed by the requested tv specification. each worker has its
+// own set of emulations
+type emulations map[string]*emulation
+
+// get the emulation for the tv specification, creating it if necessary
+func (emus emulations) get(spec string) (*emulation, error) {
+	if emu, ok := emus[spec]; ok {
+		return emu, nil
+	}
+
+	emu, err := newEmulation(spec)
+	if err != nil {
+		return nil, err
+	}
+	emus[spec] = emu
+
+	return emu, nil
+}
+
+// counts returns the total number of frames and CPU cycles emulated by the
+// emulations
+func (emus emulations) counts() (int, int) {
+	var frames, cycles int
+	for _, emu := range emus {
+		frames += emu.frames
+		cycles += emu.cycles
+	}
+	return frames, cycles
+}
+
+// end every emulation
+func (emus emulations) end() {
+	for _, emu := range emus {
+		emu.vcs.End()
+	}
+}
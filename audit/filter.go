@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/cartridgeloader"
+)
+
+// filter decides which files found during the walk of the audit path are to be
+// audited
+type filter struct {
+	// glob patterns compared with both the base name of the file and with the
+	// path relative to the audit path. if include is empty then all files are
+	// included. exclude takes priority over include
+	include []string
+	exclude []string
+
+	// file extensions in upper case with the leading period. if the list is
+	// empty then files with any extension are audited
+	extensions []string
+
+	// size bounds in bytes. zero indicates that there is no bound
+	minSize int
+	maxSize int
+}
+
+// newFilter creates a filter from the command line options. include, exclude
+// and extensions are comma separated lists. an extensions value of "*" means
+// any extension and an empty value means the extensions recognised by the
+// cartridge loader
+func newFilter(include string, exclude string, extensions string, minSize string, maxSize string) (filter, error) {
+	var flt filter
+
+	split := func(s string) []string {
+		var l []string
+		for _, p := range strings.Split(s, ",") {
+			p = strings.TrimSpace(p)
+			if p != "" {
+				l = append(l, p)
+			}
+		}
+		return l
+	}
+
+	flt.include = split(include)
+	flt.exclude = split(exclude)
+	for _, p := range slices.Concat(flt.include, flt.exclude) {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return flt, fmt.Errorf("filter: %s: %w", p, err)
+		}
+	}
+
+	switch extensions {
+	case "":
+		flt.extensions = cartridgeloader.FileExtensions
+	case "*":
+	default:
+		for _, e := range split(extensions) {
+			if !strings.HasPrefix(e, ".") {
+				e = "." + e
+			}
+			flt.extensions = append(flt.extensions, strings.ToUpper(e))
+		}
+	}
+
+	var err error
+	flt.minSize, err = parseSize(minSize)
+	if err != nil {
+		return flt, fmt.Errorf("filter: min size: %w", err)
+	}
+	flt.maxSize, err = parseSize(maxSize)
+	if err != nil {
+		return flt, fmt.Errorf("filter: max size: %w", err)
+	}
+
+	return flt, nil
+}
+
+// parseSize parses a size in bytes with an optional K or M suffix. the empty
+// string is the same as zero
+func parseSize(s string) (int, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "" {
+		return 0, nil
+	}
+
+	mult := 1
+	if v, ok := strings.CutSuffix(s, "K"); ok {
+		s = v
+		mult = 1024
+	} else if v, ok := strings.CutSuffix(s, "M"); ok {
+		s = v
+		mult = 1024 * 1024
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("not a valid size: %s", s)
+	}
+
+	return n * mult, nil
+}
+
+// match the filename against any of the glob patterns
+func matchGlobs(patterns []string, filename string) bool {
+	for _, p := range patterns {
+		if m, _ := filepath.Match(p, filepath.Base(filename)); m {
+			return true
+		}
+		if m, _ := filepath.Match(p, filename); m {
+			return true
+		}
+	}
+	return false
+}
+
+// skip returns the reason for skipping the file. the empty string indicates
+// that the file should be audited
+func (flt filter) skip(filename string, size int) string {
+	if len(flt.extensions) > 0 {
+		ext := strings.ToUpper(filepath.Ext(filename))
+		if !slices.Contains(flt.extensions, ext) {
+			return "unrecognised file extension"
+		}
+	}
+
+	if matchGlobs(flt.exclude, filename) {
+		return "excluded"
+	}
+
+	if len(flt.include) > 0 && !matchGlobs(flt.include, filename) {
+		return "not included"
+	}
+
+	if flt.minSize > 0 && size < flt.minSize {
+		return fmt.Sprintf("smaller than %d bytes", flt.minSize)
+	}
+
+	if flt.maxSize > 0 && size > flt.maxSize {
+		return fmt.Sprintf("larger than %d bytes", flt.maxSize)
+	}
+
+	return ""
+}
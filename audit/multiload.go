@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600-utils/audit/auditors"
+	"github.com/jetsetilly/gopher2600/hardware"
+)
+
+// the size of a single load in a Supercharger binary. 8k of program data
+// followed by a 256 byte header
+const superchargerLoadSize = 8448
+
+// offset of the header in a single Supercharger load and the offset of the
+// multiload number within the header
+const (
+	superchargerHeader    = 8192
+	superchargerMultiload = 5
+)
+
+// a ROM requests a load by storing the multiload number in VCS RAM and then
+// jumping to the load routine in the Supercharger BIOS. the cartridge loads
+// the requested data when the load routine is reached
+const (
+	superchargerRequest     = 0x00fa
+	superchargerLoadRoutine = 0x1850
+)
+
+// splitMultiload divides the data into Supercharger loads. returns nil if the
+// data is not a Supercharger binary with more than one load
+func splitMultiload(data []byte, mapper string) [][]byte {
+	if mapper != "AUTO" && mapper != "AR" {
+		return nil
+	}
+	if len(data) <= superchargerLoadSize || len(data)%superchargerLoadSize != 0 {
+		return nil
+	}
+
+	var parts [][]byte
+	for i := 0; i < len(data); i += superchargerLoadSize {
+		parts = append(parts, data[i:i+superchargerLoadSize])
+	}
+	return parts
+}
+
+// multiloads returns the multiload number of each load in a multiload ROM in
+// the order the loads appear in the binary. returns nil if the data is not a
+// multiload ROM
+func multiloads(data []byte, mapper string) []int {
+	parts := splitMultiload(data, mapper)
+	if parts == nil {
+		return nil
+	}
+
+	var loads []int
+	for _, p := range parts {
+		loads = append(loads, int(p[superchargerHeader+superchargerMultiload]))
+	}
+	return loads
+}
+
+// the result of auditing a single load of a multiload ROM
+type loadResult struct {
+	number int
+	res    result
+}
+
+// multiload follows the loads requested by a multiload ROM during an audit of
+// the complete binary
+type multiload struct {
+	// the multiload numbers of the loads in the binary
+	loads []int
+
+	// the load that is currently running. the first load in the binary is
+	// running when the audit starts
+	current int
+
+	// the load routine is only recognised once the CPU has executed code
+	// outside of the BIOS. this prevents the load routine being recognised
+	// more than once for the same request
+	armed bool
+
+	// results for each load in the order the loads were run
+	results []loadResult
+}
+
+func newMultiload(loads []int) *multiload {
+	return &multiload{
+		loads:   loads,
+		current: loads[0],
+	}
+}
+
+// requested checks whether the most recent CPU instruction is the start of
+// the BIOS load routine. returns the requested multiload number if it is
+func (ml *multiload) requested(vcs *hardware.VCS) (int, bool) {
+	addr := vcs.CPU.LastResult.Address
+	if vcs.Mem.Cart.GetBank(addr).IsRAM {
+		ml.armed = true
+		return 0, false
+	}
+	if !ml.armed || addr&0x1fff != superchargerLoadRoutine {
+		return 0, false
+	}
+	ml.armed = false
+
+	n, err := vcs.Mem.Peek(superchargerRequest)
+	if err != nil {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// exists returns true if the load is in the binary
+func (ml *multiload) exists(number int) bool {
+	return slices.Contains(ml.loads, number)
+}
+
+// ended records the result of the current load
+func (ml *multiload) ended(res result) {
+	ml.results = append(ml.results, loadResult{number: ml.current, res: res})
+}
+
+// result combines the results of each load that was run. the severity of the
+// result is the most severe of all the loads and the message lists the result
+// for each load in the order they were run. loads that were never requested
+// are listed at the end
+func (ml *multiload) result(auditor string) result {
+	var s []string
+	worst := auditors.Okay
+	for _, r := range ml.results {
+		s = append(s, loadMessage(r))
+		worst = max(worst, r.res.severity)
+	}
+
+	for _, n := range ml.loads {
+		if !slices.ContainsFunc(ml.results, func(r loadResult) bool { return r.number == n }) {
+			s = append(s, fmt.Sprintf("load %d: not requested", n))
+		}
+	}
+
+	return result{
+		auditor:  auditor,
+		severity: worst,
+		msg:      strings.Join(s, " | "),
+	}
+}
+
+// loadMessage describes the result of a single load
+func loadMessage(r loadResult) string {
+	if r.res.severity == auditors.Okay {
+		return fmt.Sprintf("load %d: %s", r.number, r.res.msg)
+	}
+	return fmt.Sprintf("load %d: %s: %s", r.number, r.res.severity, r.res.msg)
+}
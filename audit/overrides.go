@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/hardware/television/specification"
+)
+
+// override of the TV specification and/or the mapper for a ROM. the ROM is
+// matched by MD5 hash or by a filename glob. glob patterns are compared with
+// both the base name of the ROM file and with the path relative to the audit
+// path
+//
+// An example override file:
+//
+//	[
+//		{ "md5": "3177cc5c04c1a4080a927dfa4099482b", "tv": "PAL" },
+//		{ "filename": "*PAL*", "tv": "PAL" },
+//		{ "filename": "homebrew/*.bin", "mapper": "F8" }
+//	]
+type override struct {
+	MD5      string `json:"md5"`
+	Filename string `json:"filename"`
+	TV       string `json:"tv"`
+	Mapper   string `json:"mapper"`
+}
+
+// loadOverrides reads the overrides in the named JSON file
+func loadOverrides(filename string) ([]override, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("overrides: %w", err)
+	}
+
+	var overrides []override
+	err = json.Unmarshal(data, &overrides)
+	if err != nil {
+		return nil, fmt.Errorf("overrides: %s: %w", filename, err)
+	}
+
+	for i, o := range overrides {
+		if o.MD5 == "" && o.Filename == "" {
+			return nil, fmt.Errorf("overrides: %s: entry %d has no md5 or filename", filename, i)
+		}
+		if o.Filename != "" {
+			if _, err := filepath.Match(o.Filename, ""); err != nil {
+				return nil, fmt.Errorf("overrides: %s: %s: %w", filename, o.Filename, err)
+			}
+		}
+		if o.TV != "" {
+			var ok bool
+			overrides[i].TV, ok = specification.NormaliseReqSpecID(o.TV)
+			if !ok {
+				return nil, fmt.Errorf("overrides: %s: unsupported tv specification: %s", filename, o.TV)
+			}
+		}
+		overrides[i].MD5 = strings.ToLower(o.MD5)
+	}
+
+	return overrides, nil
+}
+
+// settings returns the TV specification and mapper to use for the ROM with
+// the hash and filename. overrides that match by MD5 hash take priority over
+// those that match by filename. otherwise the first matching override is used
+func (aud *audit) settings(hash string, filename string) (string, string) {
+	tv := aud.tv
+	mapper := aud.mapper
+
+	apply := func(o override) {
+		if o.TV != "" {
+			tv = o.TV
+		}
+		if o.Mapper != "" {
+			mapper = o.Mapper
+		}
+	}
+
+	for _, o := range aud.overrides {
+		if o.MD5 != "" && o.MD5 == hash {
+			apply(o)
+			return tv, mapper
+		}
+	}
+
+	for _, o := range aud.overrides {
+		if o.Filename != "" && matchGlobs([]string{o.Filename}, filename) {
+			apply(o)
+			return tv, mapper
+		}
+	}
+
+	return tv, mapper
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// progress shows the number of ROMs audited and an estimate of the time
+// remaining. the progress line is written to stderr. all other output must go
+// through the printf() function so that the progress line can be cleared and
+// redrawn around it
+type progress struct {
+	crit sync.Mutex
+
+	enabled bool
+	total   int
+	done    int
+	start   time.Time
+
+	// the total is not final until the walk of the files to audit has ended
+	walking bool
+}
+
+// begin showing progress. the total number of ROMs grows as ROMs are found by
+// the walk
+func (prog *progress) begin() {
+	prog.crit.Lock()
+	defer prog.crit.Unlock()
+
+	prog.total = 0
+	prog.done = 0
+	prog.walking = true
+	prog.start = time.Now()
+}
+
+// queued notes that another ROM has been found by the walk
+func (prog *progress) queued() {
+	prog.crit.Lock()
+	defer prog.crit.Unlock()
+
+	prog.clear()
+	prog.total++
+	prog.draw()
+}
+
+// walked notes that the walk has ended and that the total is final
+func (prog *progress) walked() {
+	prog.crit.Lock()
+	defer prog.crit.Unlock()
+
+	prog.walking = false
+	prog.draw()
+}
+
+// completed notes that another ROM has been audited
+func (prog *progress) completed() {
+	prog.crit.Lock()
+	defer prog.crit.Unlock()
+
+	prog.done++
+	prog.draw()
+}
+
+// end stops showing progress and removes the progress line
+func (prog *progress) end() {
+	prog.crit.Lock()
+	defer prog.crit.Unlock()
+
+	prog.clear()
+	prog.total = 0
+}
+
+// printf writes to stdout. safe to call from more than one goroutine
+func (prog *progress) printf(format string, a ...any) {
+	prog.crit.Lock()
+	defer prog.crit.Unlock()
+
+	prog.clear()
+	fmt.Printf(format, a...)
+	prog.draw()
+}
+
+// should be called with the critical section locked
+func (prog *progress) clear() {
+	if !prog.enabled || prog.total == 0 {
+		return
+	}
+	fmt.Fprint(os.Stderr, "\r\033[K")
+}
+
+// should be called with the critical section locked
+func (prog *progress) draw() {
+	if !prog.enabled || prog.total == 0 {
+		return
+	}
+
+	eta := "--"
+	if prog.done > 0 {
+		elapsed := time.Since(prog.start)
+		remaining := elapsed / time.Duration(prog.done) * time.Duration(prog.total-prog.done)
+		eta = remaining.Round(time.Second).String()
+	}
+
+	// the total is marked as provisional while the walk is in progress
+	var more string
+	if prog.walking {
+		more = "+"
+	}
+
+	fmt.Fprintf(os.Stderr, "\r%d/%d%s ROMs (%d%%) eta %s", prog.done, prog.total, more, prog.done*100/prog.total, eta)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600-utils/audit/auditors"
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/hardware/memory/chipbus"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cpubus"
+)
+
+// the values of the randomise option
+const (
+	randomiseNone = "none"
+	randomiseRAM  = "ram"
+	randomiseAll  = "all"
+)
+
+// startup describes the state of the VCS at the start of an audit. the
+// emulator's own randomisation is always disabled because it can't be seeded.
+// instead, the VCS is randomised by the audit tool using the seed
+type startup struct {
+	randomise string
+	seed      int64
+}
+
+// parseRandomise checks the value of the randomise option
+func parseRandomise(s string) (string, error) {
+	s = strings.ToLower(s)
+	switch s {
+	case randomiseNone, randomiseRAM, randomiseAll:
+		return s, nil
+	}
+	return "", fmt.Errorf("randomise: unsupported value: %s", s)
+}
+
+// withSeed returns a copy of the startup state with a different seed
+func (st startup) withSeed(seed int64) startup {
+	st.seed = seed
+	return st
+}
+
+// the TIA registers that keep their value between writes, in the order they
+// are randomised. strobe registers, such as RESP0 and HMOVE, are not included
+var randomTIA = []cpubus.Register{
+	cpubus.NUSIZ0, cpubus.NUSIZ1,
+	cpubus.COLUP0, cpubus.COLUP1, cpubus.COLUPF, cpubus.COLUBK,
+	cpubus.CTRLPF, cpubus.REFP0, cpubus.REFP1,
+	cpubus.PF0, cpubus.PF1, cpubus.PF2,
+	cpubus.AUDC0, cpubus.AUDC1, cpubus.AUDF0, cpubus.AUDF1, cpubus.AUDV0, cpubus.AUDV1,
+	cpubus.GRP0, cpubus.GRP1, cpubus.ENAM0, cpubus.ENAM1, cpubus.ENABL,
+	cpubus.HMP0, cpubus.HMP1, cpubus.HMM0, cpubus.HMM1, cpubus.HMBL,
+	cpubus.VDELP0, cpubus.VDELP1, cpubus.VDELBL, cpubus.RESMP0, cpubus.RESMP1,
+}
+
+// prepare the preferences of a new VCS. must be called before the cartridge
+// is attached
+func (st startup) prepare(vcs *hardware.VCS) {
+	// the normalised environment has the emulator's randomisation disabled and
+	// uses a fixed seed for the values of undriven data bus pins. the
+	// emulator's random pins can't be seeded so they are never enabled
+	vcs.Env.Normalise()
+}
+
+// apply the startup state to the VCS. must be called after the VCS has been
+// reset
+//
+// randomising RAM fills the 128 bytes of RIOT RAM with random values.
+// randomising all also randomises the CPU registers, with the exception of the
+// program counter, and the TIA registers in the randomTIA list
+//
+// the positions of the movable objects and the values of undriven data bus
+// pins are not randomised
+func (st startup) apply(vcs *hardware.VCS) error {
+	if st.randomise == randomiseNone {
+		return nil
+	}
+
+	rnd := rand.New(rand.NewSource(st.seed))
+
+	for addr := uint16(0x80); addr <= 0xff; addr++ {
+		err := vcs.Mem.Poke(addr, uint8(rnd.Intn(0x100)))
+		if err != nil {
+			return fmt.Errorf("randomise: %w", err)
+		}
+	}
+
+	if st.randomise == randomiseAll {
+		vcs.CPU.A.Load(uint8(rnd.Intn(0x100)))
+		vcs.CPU.X.Load(uint8(rnd.Intn(0x100)))
+		vcs.CPU.Y.Load(uint8(rnd.Intn(0x100)))
+		vcs.CPU.SP.Load(uint8(rnd.Intn(0x100)))
+		vcs.CPU.Status.Load(uint8(rnd.Intn(0x100)))
+
+		// each register is written to the TIA as though by the CPU. the TIA
+		// is stepped by a single colour clock to service each write
+		for _, r := range randomTIA {
+			vcs.TIA.Step(chipbus.ChangedRegister{
+				Address:  cpubus.WriteAddressByRegister[r],
+				Value:    uint8(rnd.Intn(0x100)),
+				Register: r,
+			}, 0)
+		}
+	}
+
+	return nil
+}
+
+// stability combines the results of running the same auditor under different
+// seeds. if the severity of the result is the same for every seed then the
+// result for the first seed is returned. otherwise the result is at least a
+// warning and the message lists the result for each seed
+func stability(seeds []int64, results []result) result {
+	res := results[0]
+
+	changed := false
+	worst := res.severity
+	for _, r := range results[1:] {
+		changed = changed || r.severity != res.severity
+		worst = max(worst, r.severity)
+	}
+
+	if !changed {
+		return res
+	}
+
+	var s strings.Builder
+	s.WriteString("verdict changes between seeds")
+	for i, r := range results {
+		s.WriteString(fmt.Sprintf(" | seed %d: %s", seeds[i], r.severity))
+	}
+
+	return result{
+		auditor:  res.auditor,
+		severity: max(worst, auditors.Warning),
+		msg:      s.String(),
+	}
+}
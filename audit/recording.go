@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/jetsetilly/gopher2600-utils/audit/auditors"
+	"github.com/jetsetilly/gopher2600/cartridgeloader"
+	"github.com/jetsetilly/gopher2600/recorder"
+)
+
+// a playback recording made by Gopher2600
+type recording struct {
+	filename string
+
+	// the TV specification the recording was made with. the ROM must be
+	// audited with the same specification for the frame digests to match
+	spec string
+
+	// the recording has been matched with a ROM
+	matched bool
+}
+
+// the recordings found in the recordings directory
+type recordings struct {
+	// recordings keyed by the SHA1 hash of the ROM in the header of the
+	// recording. this is the same hash as the HashSHA1 field of the loader
+	// for the ROM
+	hashes map[string]*recording
+
+	// files in the recordings directory that could not be read as recordings,
+	// along with the reason
+	invalid []string
+}
+
+// loadRecordings finds every playback recording in the named directory
+func loadRecordings(dir string) (*recordings, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("recordings: %w", err)
+	}
+
+	recs := &recordings{
+		hashes: make(map[string]*recording),
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+
+		fn := filepath.Join(dir, e.Name())
+		plb, err := recorder.NewPlayback(fn, false)
+		if err != nil {
+			recs.invalid = append(recs.invalid, fmt.Sprintf("%s: %v", fn, err))
+			continue
+		}
+
+		hash := plb.CartLoad.HashSHA1
+		if r, ok := recs.hashes[hash]; ok {
+			return nil, fmt.Errorf("recordings: %s and %s are for the same ROM", r.filename, fn)
+		}
+		recs.hashes[hash] = &recording{
+			filename: fn,
+			spec:     plb.TVSpec,
+		}
+	}
+
+	return recs, nil
+}
+
+// match returns the recording for the ROM in the loader. returns nil if there
+// is no recording for the ROM
+func (recs *recordings) match(loader cartridgeloader.Loader) *recording {
+	if recs == nil {
+		return nil
+	}
+	r, ok := recs.hashes[loader.HashSHA1]
+	if !ok {
+		return nil
+	}
+	r.matched = true
+	return r
+}
+
+// report lists the files that could not be read as recordings and the
+// recordings that were not matched with any of the audited ROMs
+func (recs *recordings) report(w io.Writer) {
+	if recs == nil {
+		return
+	}
+
+	for _, s := range recs.invalid {
+		fmt.Fprintf(w, "recording not read: %s\n", s)
+	}
+
+	var unmatched []string
+	for _, r := range recs.hashes {
+		if !r.matched {
+			unmatched = append(unmatched, r.filename)
+		}
+	}
+	sort.Strings(unmatched)
+	for _, fn := range unmatched {
+		fmt.Fprintf(w, "recording not matched with any ROM: %s\n", fn)
+	}
+}
+
+// attachRecording replays the recording through the VCS. the playback
+// verifies the frame digests in the recording and the emulation ends with an
+// error if the emulation does not match the recording
+//
+// the playback cannot be removed from the emulation so an emulation that has
+// had a recording attached must not be reused
+func (emu *emulation) attachRecording(rec recording) error {
+	plb, err := recorder.NewPlayback(rec.filename, false)
+	if err != nil {
+		return fmt.Errorf("recording: %w", err)
+	}
+
+	err = plb.AttachToVCSInput(emu.vcs)
+	if err != nil {
+		return fmt.Errorf("recording: %w", err)
+	}
+	emu.playback = plb
+
+	return nil
+}
+
+// playbackEnded returns true once the emulation has run past the end of the
+// recording
+func (emu *emulation) playbackEnded() (bool, error) {
+	if emu.playback == nil {
+		return false, nil
+	}
+	ended, err := emu.playback.EndFrame()
+	if err != nil {
+		return false, fmt.Errorf("recording: %w", err)
+	}
+	return ended, nil
+}
+
+// unlimitFrames removes the frame limit of an auditor with a frames
+// parameter. an audit with a recording runs for the length of the recording
+func unlimitFrames(audit auditors.Audit) error {
+	cfg, ok := audit.(auditors.Configurable)
+	if !ok {
+		return nil
+	}
+	for _, p := range cfg.Params() {
+		if p.Name == "frames" {
+			return auditors.Configure(audit, fmt.Sprintf("frames=%d", math.MaxInt32))
+		}
+	}
+	return nil
+}
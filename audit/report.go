@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"image/png"
+	"os"
+	"sort"
+
+	"github.com/jetsetilly/gopher2600-utils/audit/auditors"
+)
+
+// the information for a single ROM as used by the report template
+type reportROM struct {
+	Anchor    string
+	Filename  string
+	Names     []string
+	Hash      string
+	Mapper    string
+	Spec      string
+	Title     string
+	Thumbnail template.URL
+	Results   []reportResult
+	Findings  []reportResult
+}
+
+// the result of a single auditor as used by the report template
+type reportResult struct {
+	Auditor  string
+	Severity string
+	Msg      string
+}
+
+// writeReport writes a self-contained HTML report of all results to the named
+// file. thumbnails are embedded in the HTML as PNG data
+func (aud *audit) writeReport(filename string) error {
+	auditorIDs := aud.auditorIDs()
+
+	// ROMs that failed to load only have a result for the loader. the loader
+	// is given its own column so that the error appears in the report
+	if aud.loaderFailed() {
+		auditorIDs = append([]string{loaderAuditorID}, auditorIDs...)
+	}
+
+	results := make([]*romResult, len(aud.results))
+	copy(results, aud.results)
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].filename < results[j].filename
+	})
+
+	var roms []reportROM
+	for i, rom := range results {
+		r := reportROM{
+			Anchor:   fmt.Sprintf("rom%d", i),
+			Filename: rom.filename,
+			Names:    aud.completed[rom.hash],
+			Hash:     rom.hash,
+			Mapper:   rom.mapper,
+			Spec:     rom.spec,
+		}
+		if rom.db != nil {
+			r.Title = rom.db.title
+		}
+
+		if rom.thumbnail != nil {
+			var b bytes.Buffer
+			err := png.Encode(&b, rom.thumbnail)
+			if err != nil {
+				return fmt.Errorf("report: %w", err)
+			}
+			r.Thumbnail = template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(b.Bytes()))
+		}
+
+		// results are presented in the same order as the auditor columns
+		for _, id := range auditorIDs {
+			res := reportResult{Auditor: id, Severity: auditors.Error.String(), Msg: "not run"}
+			if id == loaderAuditorID {
+				res = reportResult{Auditor: id, Severity: auditors.Okay.String(), Msg: "ok"}
+			}
+			for _, rr := range rom.results {
+				if rr.auditor == id {
+					res = reportResult{Auditor: rr.auditor, Severity: rr.severity.String(), Msg: rr.msg}
+					break
+				}
+			}
+			r.Results = append(r.Results, res)
+			if res.Severity != auditors.Okay.String() {
+				r.Findings = append(r.Findings, res)
+			}
+		}
+
+		roms = append(roms, r)
+	}
+
+	f, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("report: %w", err)
+	}
+	defer f.Close()
+
+	err = reportTemplate.Execute(f, struct {
+		Auditors []string
+		ROMs     []reportROM
+	}{
+		Auditors: auditorIDs,
+		ROMs:     roms,
+	})
+	if err != nil {
+		return fmt.Errorf("report: %w", err)
+	}
+
+	return nil
+}
+
+// loaderFailed returns true if any ROM failed to load
+func (aud *audit) loaderFailed() bool {
+	for _, rom := range aud.results {
+		for _, res := range rom.results {
+			if res.auditor == loaderAuditorID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Gopher2600 Audit</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+th { background: #eee; cursor: pointer; }
+td.okay { background: #dfd; }
+td.warning { background: #ffd; }
+td.error { background: #fdd; }
+img { width: 160px; image-rendering: pixelated; }
+section { margin-top: 2em; }
+</style>
+</head>
+<body>
+<h1>Gopher2600 Audit</h1>
+<p>{{len .ROMs}} ROMs. Click a column heading to sort the table.</p>
+<table id="results">
+<thead>
+<tr><th>ROM</th><th>Mapper</th><th>TV</th>{{range .Auditors}}<th>{{.}}</th>{{end}}<th>Last Frame</th></tr>
+</thead>
+<tbody>
+{{range .ROMs}}{{$anchor := .Anchor}}<tr>
+<td><a href="#{{.Anchor}}">{{.Filename}}</a></td><td>{{.Mapper}}</td><td>{{.Spec}}</td>
+{{range .Results}}{{if eq .Severity "okay"}}<td class="okay">{{.Msg}}</td>{{else}}<td class="{{.Severity}}"><a href="#{{$anchor}}">{{.Msg}}</a></td>{{end}}{{end}}
+<td>{{if .Thumbnail}}<img src="{{.Thumbnail}}">{{end}}</td>
+</tr>
+{{end}}</tbody>
+</table>
+{{range .ROMs}}
+<section id="{{.Anchor}}">
+<h2>{{.Filename}}</h2>
+{{if .Title}}<p>{{.Title}}</p>{{end}}
+<p>MD5: {{.Hash}}<br>Mapper: {{.Mapper}}<br>TV: {{.Spec}}</p>
+{{if gt (len .Names) 1}}<p>Also found as: {{range $i, $n := .Names}}{{if $i}}{{$n}} {{end}}{{end}}</p>{{end}}
+{{if .Findings}}<ul>{{range .Findings}}<li><b>{{.Auditor}}</b> ({{.Severity}}): {{.Msg}}</li>{{end}}</ul>{{else}}<p>No findings</p>{{end}}
+{{if .Thumbnail}}<img src="{{.Thumbnail}}" style="width: 320px">{{end}}
+</section>
+{{end}}
+<script>
+document.querySelectorAll("#results th").forEach(function(th, col) {
+	var asc = true;
+	th.addEventListener("click", function() {
+		var tbody = document.querySelector("#results tbody");
+		var rows = Array.from(tbody.rows);
+		rows.sort(function(a, b) {
+			var x = a.cells[col].textContent;
+			var y = b.cells[col].textContent;
+			return asc ? x.localeCompare(y, undefined, {numeric: true}) : y.localeCompare(x, undefined, {numeric: true});
+		});
+		asc = !asc;
+		rows.forEach(function(r) { tbody.appendChild(r); });
+	});
+});
+</script>
+</body>
+</html>
+`))
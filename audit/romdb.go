@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600-utils/audit/auditors"
+	"github.com/jetsetilly/gopher2600/hardware/riot/ports/plugging"
+	"github.com/jetsetilly/gopher2600/hardware/television/specification"
+)
+
+// the ID used for the result of the database lookup
+const databaseAuditorID = "Database"
+
+// the information about a single ROM in the database. any field can be empty
+type romEntry struct {
+	title        string
+	manufacturer string
+	year         string
+
+	// the expected controller in the left port. the value is a
+	// plugging.PeripheralID if the database value is recognised
+	controller string
+
+	// the expected tv specification. normalised to a value in
+	// specification.ReqSpecList if the database value is recognised
+	tv string
+}
+
+// the ROM database is keyed by the lower case MD5 hash of the ROM data
+type romDatabase map[string]romEntry
+
+// loadDatabase reads the named ROM database. two formats are supported: a
+// Stella properties file and an XML DAT file in the Logiqx format used by
+// No-Intro. the format is decided by the content of the file
+func loadDatabase(filename string) (romDatabase, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("database: %w", err)
+	}
+
+	var db romDatabase
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		db, err = parseDat(data)
+	} else {
+		db, err = parseProperties(bytes.NewReader(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database: %s: %w", filename, err)
+	}
+
+	return db, nil
+}
+
+// stella controller names and the equivalent peripheral ID
+var stellaControllers = map[string]plugging.PeripheralID{
+	"JOYSTICK":      plugging.PeriphStick,
+	"PADDLES":       plugging.PeriphPaddles,
+	"PADDLES_IAXIS": plugging.PeriphPaddles,
+	"PADDLES_IAXDR": plugging.PeriphPaddles,
+	"KEYBOARD":      plugging.PeriphKeypad,
+	"GENESIS":       plugging.PeriphGamepad,
+	"SAVEKEY":       plugging.PeriphSavekey,
+	"ATARIVOX":      plugging.PeriphAtariVox,
+}
+
+// parseProperties parses a Stella properties file. entries start with a
+// Cart.MD5 line and every line is a quoted key and a quoted value:
+//
+//	"Cart.MD5" "f0e0addc07971561ab80d9abe1b8d333"
+//	"Cart.Manufacturer" "Imagic"
+//	"Cart.Name" "Demon Attack (1982) (Imagic)"
+//	"Controller.Left" "JOYSTICK"
+//	"Display.Format" "NTSC"
+//	""
+//
+// the year is not a field in the properties file and is taken from the name of
+// the ROM if possible
+func parseProperties(r io.Reader) (romDatabase, error) {
+	db := make(romDatabase)
+
+	var hash string
+	var entry romEntry
+
+	commit := func() {
+		if hash != "" {
+			if entry.year == "" {
+				entry.year = yearFromName(entry.title)
+			}
+			db[hash] = entry
+		}
+		hash = ""
+		entry = romEntry{}
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		flds := strings.SplitN(strings.TrimSpace(scanner.Text()), " ", 2)
+		if len(flds) < 2 {
+			continue // for loop
+		}
+
+		key := strings.ToUpper(strings.Trim(flds[0], "\""))
+		value := strings.Trim(strings.TrimSpace(flds[1]), "\"")
+
+		switch key {
+		case "CART.MD5":
+			commit()
+			if len(value) == 32 {
+				hash = strings.ToLower(value)
+			}
+		case "CART.NAME":
+			entry.title = value
+		case "CART.MANUFACTURER":
+			entry.manufacturer = value
+		case "CONTROLLER.LEFT":
+			if id, ok := stellaControllers[strings.ToUpper(value)]; ok {
+				entry.controller = string(id)
+			} else {
+				entry.controller = value
+			}
+		case "DISPLAY.FORMAT":
+			entry.tv = normaliseDatabaseSpec(value)
+		}
+	}
+	commit()
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// the parts of the Logiqx XML format that are used by parseDat()
+type datFile struct {
+	Games []datGame `xml:"game"`
+
+	// some DAT files use machine rather than game
+	Machines []datGame `xml:"machine"`
+}
+
+type datGame struct {
+	Name         string   `xml:"name,attr"`
+	Description  string   `xml:"description"`
+	Year         string   `xml:"year"`
+	Manufacturer string   `xml:"manufacturer"`
+	ROMs         []datROM `xml:"rom"`
+}
+
+type datROM struct {
+	MD5 string `xml:"md5,attr"`
+}
+
+// parseDat parses an XML DAT file. the tv specification is taken from the name
+// of the game, which usually includes the region
+func parseDat(data []byte) (romDatabase, error) {
+	var dat datFile
+	err := xml.Unmarshal(data, &dat)
+	if err != nil {
+		return nil, err
+	}
+
+	db := make(romDatabase)
+	for _, g := range slices.Concat(dat.Games, dat.Machines) {
+		title := g.Description
+		if title == "" {
+			title = g.Name
+		}
+
+		entry := romEntry{
+			title:        title,
+			manufacturer: g.Manufacturer,
+			year:         g.Year,
+			tv:           normaliseDatabaseSpec(specification.SearchReqSpec(g.Name)),
+		}
+		if entry.year == "" {
+			entry.year = yearFromName(title)
+		}
+
+		for _, r := range g.ROMs {
+			if len(r.MD5) == 32 {
+				db[strings.ToLower(r.MD5)] = entry
+			}
+		}
+	}
+
+	return db, nil
+}
+
+// normaliseDatabaseSpec returns the empty string if the tv specification is not
+// recognised or if it is AUTO
+func normaliseDatabaseSpec(spec string) string {
+	spec, ok := specification.NormaliseReqSpecID(spec)
+	if !ok || spec == "AUTO" {
+		return ""
+	}
+	return spec
+}
+
+// a year in brackets as used in the names of ROMs in ROM collections
+var nameYear = regexp.MustCompile(`\((19[789]\d|20\d\d)\)`)
+
+// yearFromName returns the year found in the name of a ROM or the empty string
+func yearFromName(name string) string {
+	m := nameYear.FindStringSubmatch(name)
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+// checkDatabase compares the database entry for the ROM with what was observed
+// during the audit. mismatches are reported as warnings
+func (aud *audit) checkDatabase(rom *romResult) result {
+	res := result{auditor: databaseAuditorID}
+
+	entry, ok := aud.database[rom.hash]
+	if !ok {
+		res.msg = "not in database"
+		return res
+	}
+	rom.db = &entry
+
+	var mismatches []string
+
+	// the observed specification for the -specs mode is a list of the
+	// specifications that produced a stable picture. a PAL60 ROM uses the PAL
+	// palette and so is observed as PAL
+	if entry.tv != "" && rom.spec != "" {
+		expected := entry.tv
+		if expected == "PAL60" {
+			expected = "PAL"
+		}
+		if !slices.Contains(strings.Split(rom.spec, "/"), expected) {
+			mismatches = append(mismatches, fmt.Sprintf("database expects %s but emulation settled on %s", entry.tv, rom.spec))
+		}
+	}
+
+	if entry.controller != "" && rom.controller != "" && entry.controller != rom.controller {
+		mismatches = append(mismatches, fmt.Sprintf("database expects %s controller but %s was plugged", entry.controller, rom.controller))
+	}
+
+	if len(mismatches) > 0 {
+		res.severity = auditors.Warning
+		res.msg = strings.Join(mismatches, " | ")
+		return res
+	}
+
+	res.msg = entry.title
+	if entry.manufacturer != "" || entry.year != "" {
+		res.msg = fmt.Sprintf("%s [%s]", res.msg, strings.TrimSpace(entry.manufacturer+" "+entry.year))
+	}
+
+	return res
+}
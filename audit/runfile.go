@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jetsetilly/gopher2600-utils/audit/auditors"
+)
+
+// savedRun is the JSON representation of an audit run. saved runs can be
+// compared with the diff subcommand
+type savedRun struct {
+	Auditors []string   `json:"auditors"`
+	ROMs     []savedROM `json:"roms"`
+}
+
+type savedROM struct {
+	Filename string        `json:"filename"`
+	Names    []string      `json:"names"`
+	Hash     string        `json:"hash"`
+	Mapper   string        `json:"mapper"`
+	Spec     string        `json:"spec"`
+	Results  []savedResult `json:"results"`
+
+	// information from the ROM database
+	Title        string `json:"title,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Year         string `json:"year,omitempty"`
+}
+
+type savedResult struct {
+	Auditor  string `json:"auditor"`
+	Ok       bool   `json:"ok"`
+	Severity string `json:"severity"`
+	Msg      string `json:"msg"`
+}
+
+// writeRun saves the results of the audit to the named file as JSON
+func (aud *audit) writeRun(filename string) error {
+	run := savedRun{
+		Auditors: aud.auditorIDs(),
+	}
+
+	for _, rom := range aud.results {
+		r := savedROM{
+			Filename: rom.filename,
+			Names:    aud.completed[rom.hash],
+			Hash:     rom.hash,
+			Mapper:   rom.mapper,
+			Spec:     rom.spec,
+		}
+		if rom.db != nil {
+			r.Title = rom.db.title
+			r.Manufacturer = rom.db.manufacturer
+			r.Year = rom.db.year
+		}
+		for _, res := range rom.results {
+			r.Results = append(r.Results, savedResult{
+				Auditor:  res.auditor,
+				Ok:       res.severity == auditors.Okay,
+				Severity: res.severity.String(),
+				Msg:      res.msg,
+			})
+		}
+		run.ROMs = append(run.ROMs, r)
+	}
+
+	data, err := json.MarshalIndent(run, "", "\t")
+	if err != nil {
+		return fmt.Errorf("json: %w", err)
+	}
+
+	err = os.WriteFile(filename, data, 0644)
+	if err != nil {
+		return fmt.Errorf("json: %w", err)
+	}
+
+	return nil
+}
+
+// readRun loads a run previously saved with writeRun()
+func readRun(filename string) (savedRun, error) {
+	var run savedRun
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return run, fmt.Errorf("json: %w", err)
+	}
+
+	err = json.Unmarshal(data, &run)
+	if err != nil {
+		return run, fmt.Errorf("json: %s: %w", filename, err)
+	}
+
+	return run, nil
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600/hardware"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cpubus"
+	"github.com/jetsetilly/gopher2600/hardware/television/frameinfo"
+	"github.com/jetsetilly/gopher2600/hardware/television/signal"
+)
+
+// the most snapshots that will be taken by a single run of an auditor
+const snapshotLimit = 8
+
+// frameCapture implements the television.PixelRenderer interface and keeps
+// the signals most recently sent by the television. the signals are used to
+// draw the frame up to the point at which a snapshot is taken
+type frameCapture struct {
+	frameInfo frameinfo.Current
+	sig       []signal.SignalAttributes
+}
+
+// NewFrame implements the television.PixelRenderer() interface
+func (fc *frameCapture) NewFrame(frameInfo frameinfo.Current) error {
+	fc.frameInfo = frameInfo
+	return nil
+}
+
+// NewScanline implements the television.PixelRenderer() interface
+func (fc *frameCapture) NewScanline(scanline int) error {
+	return nil
+}
+
+// SetPixels implements the television.PixelRenderer() interface
+func (fc *frameCapture) SetPixels(sig []signal.SignalAttributes, last int) error {
+	fc.sig = sig
+	return nil
+}
+
+// Reset implements the television.PixelRenderer() interface
+func (fc *frameCapture) Reset() {
+}
+
+// EndRendering implements the television.PixelRenderer() interface
+func (fc *frameCapture) EndRendering() error {
+	return nil
+}
+
+// writeCapture keeps the value last written to each TIA and RIOT register.
+// the values written to the TIA registers can not be read back by the CPU so
+// they are kept as they are written by the ROM
+type writeCapture struct {
+	registers map[uint16]cpubus.Register
+	last      map[cpubus.Register]uint8
+
+	// the register that most recently started the RIOT timer. empty if the
+	// timer has not been started by the ROM
+	timer cpubus.Register
+}
+
+func newWriteCapture() *writeCapture {
+	wc := &writeCapture{
+		registers: make(map[uint16]cpubus.Register),
+		last:      make(map[cpubus.Register]uint8),
+	}
+	for r, addr := range cpubus.WriteAddressByRegister {
+		wc.registers[addr] = r
+	}
+	return wc
+}
+
+// step should be called after every CPU instruction
+func (wc *writeCapture) step(vcs *hardware.VCS) {
+	if !vcs.Mem.LastCPUWrite {
+		return
+	}
+	r, ok := wc.registers[vcs.Mem.LastCPUAddressMapped]
+	if !ok {
+		return
+	}
+	wc.last[r] = vcs.Mem.LastCPUData
+
+	switch r {
+	case cpubus.TIM1T, cpubus.TIM8T, cpubus.TIM64T, cpubus.T1024T:
+		wc.timer = r
+	}
+}
+
+// the state of the emulation saved by a snapshot. the state is a standalone
+// record of the emulation for reading and can't be loaded by the Gopher2600
+// debugger
+type snapshotState struct {
+	Auditor  string `json:"auditor"`
+	Reason   string `json:"reason"`
+	Filename string `json:"filename"`
+	Hash     string `json:"hash"`
+	Mapper   string `json:"mapper"`
+	Bank     int    `json:"bank"`
+
+	// television coordinates
+	Frame    int `json:"frame"`
+	Scanline int `json:"scanline"`
+	Clock    int `json:"clock"`
+
+	CPU struct {
+		PC     string `json:"pc"`
+		A      string `json:"a"`
+		X      string `json:"x"`
+		Y      string `json:"y"`
+		SP     string `json:"sp"`
+		Status string `json:"status"`
+	} `json:"cpu"`
+
+	// the 128 bytes of VCS RAM as eight rows of sixteen bytes, each row
+	// prefixed with the address of the first byte in the row
+	RAM []string `json:"ram"`
+
+	// the TIA and RIOT registers as they would be read by the CPU
+	Registers map[string]string `json:"registers"`
+
+	// the value last written to each TIA and RIOT register. registers that
+	// have not been written to by the ROM are not included
+	Writes map[string]string `json:"writes"`
+
+	// the horizontal position of each movable object as it is in the TIA,
+	// including the effect of any HMOVE
+	Positions struct {
+		Player0  int `json:"player0"`
+		Player1  int `json:"player1"`
+		Missile0 int `json:"missile0"`
+		Missile1 int `json:"missile1"`
+		Ball     int `json:"ball"`
+	} `json:"positions"`
+
+	// the register that started the RIOT timer, the value written to it and
+	// the current value of the timer
+	Timer struct {
+		Interval string `json:"interval"`
+		Initial  string `json:"initial"`
+		INTIM    string `json:"intim"`
+		TIMINT   string `json:"timint"`
+	} `json:"timer"`
+}
+
+// snapshot saves the state of the emulation and an image of the frame so far
+// to the snapshot directory. each ROM has its own directory, named after the
+// hash of the ROM in the audit results
+func (aud *audit) snapshot(emu *emulation, capture *frameCapture, writes *writeCapture, rom *romResult, auditor string, reason string) error {
+	vcs := emu.vcs
+
+	var st snapshotState
+	st.Auditor = auditor
+	st.Reason = reason
+	st.Filename = rom.filename
+	st.Hash = rom.hash
+	st.Mapper = vcs.Mem.Cart.ID()
+	st.Bank = vcs.Mem.Cart.GetBank(vcs.CPU.PC.Address()).Number
+
+	coords := emu.tv.GetCoords()
+	st.Frame = coords.Frame
+	st.Scanline = coords.Scanline
+	st.Clock = coords.Clock
+
+	st.CPU.PC = fmt.Sprintf("$%04x", vcs.CPU.PC.Address())
+	st.CPU.A = fmt.Sprintf("$%02x", vcs.CPU.A.Value())
+	st.CPU.X = fmt.Sprintf("$%02x", vcs.CPU.X.Value())
+	st.CPU.Y = fmt.Sprintf("$%02x", vcs.CPU.Y.Value())
+	st.CPU.SP = fmt.Sprintf("$%02x", vcs.CPU.SP.Value())
+	st.CPU.Status = fmt.Sprintf("$%02x", vcs.CPU.Status.Value())
+
+	for row := uint16(0x80); row <= 0xf0; row += 0x10 {
+		var s strings.Builder
+		s.WriteString(fmt.Sprintf("$%02x:", row))
+		for addr := row; addr < row+0x10; addr++ {
+			v, err := vcs.Mem.Peek(addr)
+			if err != nil {
+				return fmt.Errorf("snapshot: %w", err)
+			}
+			s.WriteString(fmt.Sprintf(" %02x", v))
+		}
+		st.RAM = append(st.RAM, s.String())
+	}
+
+	st.Registers = make(map[string]string)
+	for r, addr := range cpubus.ReadAddressByRegister {
+		v, err := vcs.Mem.Peek(addr)
+		if err != nil {
+			return fmt.Errorf("snapshot: %w", err)
+		}
+		st.Registers[string(r)] = fmt.Sprintf("$%02x", v)
+	}
+
+	st.Writes = make(map[string]string)
+	for r, v := range writes.last {
+		st.Writes[string(r)] = fmt.Sprintf("$%02x", v)
+	}
+	st.Positions.Player0 = vcs.TIA.Video.Player0.HmovedPixel
+	st.Positions.Player1 = vcs.TIA.Video.Player1.HmovedPixel
+	st.Positions.Missile0 = vcs.TIA.Video.Missile0.HmovedPixel
+	st.Positions.Missile1 = vcs.TIA.Video.Missile1.HmovedPixel
+	st.Positions.Ball = vcs.TIA.Video.Ball.HmovedPixel
+
+	if writes.timer != "" {
+		st.Timer.Interval = string(writes.timer)
+		st.Timer.Initial = fmt.Sprintf("$%02x", writes.last[writes.timer])
+	}
+	st.Timer.INTIM = st.Registers[string(cpubus.INTIM)]
+	st.Timer.TIMINT = st.Registers[string(cpubus.TIMINT)]
+
+	dir := filepath.Join(aud.snapshots, st.Hash)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+
+	// snapshots are numbered in the order they are taken for the ROM
+	rom.snapshots++
+	base := filepath.Join(dir, fmt.Sprintf("%s_%03d", auditor, rom.snapshots))
+
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	err = os.WriteFile(base+".json", data, 0644)
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+
+	// the frame is drawn up to the current position of the television
+	last := emu.tv.GetLastSignal().Index
+	img := drawSignals(nil, capture.frameInfo, capture.sig, last)
+	if img == nil {
+		return nil
+	}
+
+	err = writePNG(base+".png", img)
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+
+	return nil
+}
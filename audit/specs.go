@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/jetsetilly/gopher2600-utils/audit/auditors"
+	"github.com/jetsetilly/gopher2600/cartridgeloader"
+	"github.com/jetsetilly/gopher2600/debugger/govern"
+	"github.com/jetsetilly/gopher2600/hardware/memory/cpubus"
+)
+
+// the TV specifications compared by the -specs mode
+var specList = []string{"NTSC", "PAL", "PAL-M", "SECAM"}
+
+// the specification that lets the TV choose the specification from the number
+// of scanlines in the frame
+const specAuto = "AUTO"
+
+// the ID used for the results of the -specs mode
+const specsAuditorID = "TVSpecs"
+
+// the number of frames each ROM is run for under each TV specification
+const specsFrames = 60
+
+// sentinal error used to end a probe after specsFrames
+var probeEnded = errors.New("probe ended")
+
+// the result of running a ROM under a single TV specification
+type specProbe struct {
+	mapper    string
+	stable    bool
+	scanlines int
+
+	// the set of values written to the COLUxx registers
+	colours map[uint8]bool
+
+	// the specification of each frame in the order they were seen. only
+	// changes of specification are recorded
+	specs []string
+}
+
+// probeSpec runs the ROM under the TV specification with the colour switch
+// set as specified and with the startup state. the thumbnail argument can be
+// nil
+func probeSpec(ctx context.Context, emus emulations, loader cartridgeloader.Loader, spec string, colour bool, thmb *thumbnail, st startup) (specProbe, error) {
+	probe := specProbe{
+		colours: make(map[uint8]bool),
+	}
+
+	emu, err := emus.get(spec)
+	if err != nil {
+		return probe, err
+	}
+
+	err = emu.attach(loader, st, colour)
+	if err != nil {
+		return probe, err
+	}
+
+	if thmb != nil {
+		emu.tv.AddPixelRenderer(thmb)
+		defer emu.tv.RemovePixelRenderer(thmb)
+	}
+
+	colu := []uint16{
+		cpubus.WriteAddressByRegister[cpubus.COLUPF],
+		cpubus.WriteAddressByRegister[cpubus.COLUBK],
+		cpubus.WriteAddressByRegister[cpubus.COLUP0],
+		cpubus.WriteAddressByRegister[cpubus.COLUP1],
+	}
+
+	vcs := emu.vcs
+	err = vcs.Run(func() (govern.State, error) {
+		emu.cycles += vcs.CPU.LastResult.Cycles
+		if err := ctx.Err(); err != nil {
+			return govern.Ending, err
+		}
+		info := emu.tv.GetFrameInfo()
+		if info.FrameNum > specsFrames {
+			return govern.Ending, probeEnded
+		}
+		if len(probe.specs) == 0 || probe.specs[len(probe.specs)-1] != info.Spec.ID {
+			probe.specs = append(probe.specs, info.Spec.ID)
+		}
+		if vcs.Mem.LastCPUWrite {
+			for _, addr := range colu {
+				if vcs.Mem.LastCPUAddressMapped == addr {
+					probe.colours[vcs.Mem.LastCPUData] = true
+					break
+				}
+			}
+		}
+		return govern.Running, nil
+	})
+	emu.frames += emu.tv.GetCoords().Frame
+	if err != nil && !errors.Is(err, probeEnded) {
+		return probe, err
+	}
+
+	info := emu.tv.GetFrameInfo()
+	probe.mapper = vcs.Mem.Cart.ID()
+	probe.stable = info.Stable
+	probe.scanlines = info.TotalScanlines
+
+	return probe, nil
+}
+
+// compareSpecs runs the ROM under every TV specification in specList. the
+// result reports which specifications produce a stable picture and the number
+// of scanlines in each case
+//
+// the result also reports whether the ROM responds to the colour/B&W switch.
+// this is decided by comparing the set of values written to the COLUxx
+// registers and so should be considered a heuristic
+//
+// finally, the ROM is run with the AUTO specification and the result reports
+// the specification chosen by the TV and whether it changes during the run. a
+// forced specification that is not kept by every frame is also reported
+func (aud *audit) compareSpecs(ctx context.Context, emus emulations, loader cartridgeloader.Loader, rom *romResult) result {
+	res := result{auditor: specsAuditorID}
+
+	var s strings.Builder
+	var stable []string
+	var respondsToBW bool
+	var notKept []string
+
+	for i, spec := range specList {
+		// thumbnail is only required for the HTML report and only for the
+		// first specification
+		var thmb *thumbnail
+		if aud.html != "" && i == 0 {
+			thmb = &thumbnail{}
+		}
+
+		colour, err := probeSpec(ctx, emus, loader, spec, true, thmb, aud.startup)
+		if err != nil {
+			res.severity = auditors.Error
+			res.msg = fmt.Sprintf("%s: %s", spec, err.Error())
+			return res
+		}
+
+		bw, err := probeSpec(ctx, emus, loader, spec, false, nil, aud.startup)
+		if err != nil {
+			res.severity = auditors.Error
+			res.msg = fmt.Sprintf("%s: %s", spec, err.Error())
+			return res
+		}
+
+		rom.mapper = colour.mapper
+		if thmb != nil && thmb.img != nil {
+			rom.thumbnail = thmb.img
+		}
+
+		respondsToBW = respondsToBW || !maps.Equal(colour.colours, bw.colours)
+		if slices.ContainsFunc(colour.specs, func(id string) bool { return id != spec }) {
+			notKept = append(notKept, spec)
+		}
+
+		if colour.stable {
+			stable = append(stable, spec)
+			s.WriteString(fmt.Sprintf("%s: %d stable | ", spec, colour.scanlines))
+		} else {
+			s.WriteString(fmt.Sprintf("%s: %d unstable | ", spec, colour.scanlines))
+		}
+	}
+
+	auto, err := probeSpec(ctx, emus, loader, specAuto, true, nil, aud.startup)
+	if err != nil {
+		res.severity = auditors.Error
+		res.msg = fmt.Sprintf("%s: %s", specAuto, err.Error())
+		return res
+	}
+
+	s.WriteString(fmt.Sprintf("B&W switch: %v | auto: %s", respondsToBW, strings.Join(auto.specs, " > ")))
+	if len(auto.specs) > 1 {
+		s.WriteString(" (changes)")
+	}
+	if len(notKept) > 0 {
+		s.WriteString(fmt.Sprintf(" | frame spec differs from forced: %s", strings.Join(notKept, ", ")))
+	}
+
+	rom.spec = strings.Join(stable, "/")
+	res.msg = s.String()
+	if len(stable) == 0 {
+		res.severity = auditors.Error
+	}
+
+	return res
+}
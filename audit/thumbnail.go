@@ -0,0 +1,97 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+
+	"github.com/jetsetilly/gopher2600/hardware/television/frameinfo"
+	"github.com/jetsetilly/gopher2600/hardware/television/signal"
+	"github.com/jetsetilly/gopher2600/hardware/television/specification"
+)
+
+// thumbnail implements the television.PixelRenderer interface and keeps an
+// image of the visible area of the most recent frame
+type thumbnail struct {
+	frameInfo frameinfo.Current
+	img       *image.RGBA
+}
+
+// NewFrame implements the television.PixelRenderer() interface
+func (thmb *thumbnail) NewFrame(frameInfo frameinfo.Current) error {
+	thmb.frameInfo = frameInfo
+	return nil
+}
+
+// NewScanline implements the television.PixelRenderer() interface
+func (thmb *thumbnail) NewScanline(scanline int) error {
+	return nil
+}
+
+// SetPixels implements the television.PixelRenderer() interface
+func (thmb *thumbnail) SetPixels(sig []signal.SignalAttributes, last int) error {
+	thmb.img = drawSignals(thmb.img, thmb.frameInfo, sig, last)
+	return nil
+}
+
+// drawSignals draws the visible area of the frame described by the signals to
+// the image. a new image is created if the image is nil or is the wrong size
+// for the visible area. the image is returned unchanged if the visible area
+// is empty
+func drawSignals(img *image.RGBA, frameInfo frameinfo.Current, sig []signal.SignalAttributes, last int) *image.RGBA {
+	top := frameInfo.VisibleTop
+	bottom := frameInfo.VisibleBottom
+	if bottom <= top {
+		return img
+	}
+
+	rect := image.Rect(0, 0, specification.ClksVisible, bottom-top)
+	if img == nil || img.Bounds() != rect {
+		img = image.NewRGBA(rect)
+	}
+
+	for i := 0; i <= last && i < len(sig); i++ {
+		s := sig[i]
+		if s.Index == signal.NoSignal {
+			continue
+		}
+
+		x := s.Index%specification.ClksScanline - specification.ClksHBlank
+		y := s.Index/specification.ClksScanline - top
+		if x < 0 || y < 0 || y >= rect.Max.Y {
+			continue
+		}
+
+		// pixels in VBLANK are drawn as black in the same way as a real TV
+		var rgb color.RGBA
+		if s.VBlank {
+			rgb = frameInfo.Spec.GetColor(signal.VideoBlack)
+		} else {
+			rgb = frameInfo.Spec.GetColor(s.Color)
+		}
+		img.SetRGBA(x, y, rgb)
+	}
+
+	return img
+}
+
+// Reset implements the television.PixelRenderer() interface
+func (thmb *thumbnail) Reset() {
+}
+
+// EndRendering implements the television.PixelRenderer() interface
+func (thmb *thumbnail) EndRendering() error {
+	return nil
+}
+
+// writePNG encodes the image as a PNG file
+func writePNG(filename string, img image.Image) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return png.Encode(f, img)
+}